@@ -0,0 +1,269 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// IDSource yields account IDs one at a time. Next returns io.EOF once every
+// ID has been produced.
+type IDSource interface {
+	Next() (tbTypes.Uint128, error)
+}
+
+// IDRange is an inclusive range of IDs.
+type IDRange struct {
+	First tbTypes.Uint128
+	Last  tbTypes.Uint128
+}
+
+// ParseIDRange parses a single ID, or an inclusive range written as
+// "first..last". Both ends accept the forms models.ParseUint128 does.
+func ParseIDRange(s string) (IDRange, error) {
+	parts := strings.SplitN(s, "..", 2)
+	first, err := models.ParseUint128(parts[0])
+	if err != nil {
+		return IDRange{}, fmt.Errorf("invalid id range %q: %w", s, err)
+	}
+	if len(parts) == 1 {
+		return IDRange{First: first, Last: first}, nil
+	}
+	last, err := models.ParseUint128(parts[1])
+	if err != nil {
+		return IDRange{}, fmt.Errorf("invalid id range %q: %w", s, err)
+	}
+	firstInt, lastInt := first.BigInt(), last.BigInt()
+	if firstInt.Cmp(&lastInt) > 0 {
+		return IDRange{}, fmt.Errorf("invalid id range %q: first id is greater than last", s)
+	}
+	return IDRange{First: first, Last: last}, nil
+}
+
+type rangeSource struct {
+	ranges []IDRange
+	next   *big.Int
+}
+
+// NewRangeSource returns an IDSource that counts through each range in turn,
+// without materialising the IDs.
+func NewRangeSource(ranges []IDRange) IDSource {
+	return &rangeSource{ranges: ranges}
+}
+
+func (s *rangeSource) Next() (tbTypes.Uint128, error) {
+	for len(s.ranges) > 0 {
+		if s.next == nil {
+			first := s.ranges[0].First.BigInt()
+			s.next = &first
+		}
+		last := s.ranges[0].Last.BigInt()
+		if s.next.Cmp(&last) <= 0 {
+			id := tbTypes.BigIntToUint128(*s.next)
+			s.next.Add(s.next, big.NewInt(1))
+			return id, nil
+		}
+		s.ranges = s.ranges[1:]
+		s.next = nil
+	}
+	return tbTypes.Uint128{}, io.EOF
+}
+
+type listSource struct {
+	scanner *bufio.Scanner
+	line    int
+	current IDSource
+}
+
+// NewListSource returns an IDSource that reads one ID or range per line from
+// r. Blank lines and lines starting with # are skipped.
+func NewListSource(r io.Reader) IDSource {
+	return &listSource{scanner: bufio.NewScanner(r)}
+}
+
+func (s *listSource) Next() (tbTypes.Uint128, error) {
+	for {
+		if s.current != nil {
+			id, err := s.current.Next()
+			if err != io.EOF {
+				return id, err
+			}
+			s.current = nil
+		}
+
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil {
+				return tbTypes.Uint128{}, fmt.Errorf("error reading ids: %w", err)
+			}
+			return tbTypes.Uint128{}, io.EOF
+		}
+		s.line++
+
+		text := strings.TrimSpace(s.scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r, err := ParseIDRange(text)
+		if err != nil {
+			return tbTypes.Uint128{}, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		s.current = NewRangeSource([]IDRange{r})
+	}
+}
+
+// createdRun tracks a run of consecutive created IDs, so they can be
+// reported as one range.
+type createdRun struct {
+	first, last *big.Int
+}
+
+func (r *createdRun) add(id tbTypes.Uint128) {
+	value := id.BigInt()
+	if r.last != nil && new(big.Int).Add(r.last, big.NewInt(1)).Cmp(&value) == 0 {
+		r.last = &value
+		return
+	}
+	r.flush()
+	r.first, r.last = &value, &value
+}
+
+func (r *createdRun) flush() {
+	switch {
+	case r.first == nil:
+		return
+	case r.first.Cmp(r.last) == 0:
+		fmt.Printf("Account %s: created\n", r.first)
+	default:
+		fmt.Printf("Accounts %s..%s: created\n", r.first, r.last)
+	}
+	r.first, r.last = nil, nil
+}
+
+// CreateAccounts creates an account for every ID from ids, sharing ledger,
+// code and flags, in batches. Every ID is reported as created, already
+// existing or failed with the cluster's result; runs of consecutive created
+// IDs are reported as a range. Rejected accounts do not stop the remaining
+// batches.
+func (t *TigerBeagle) CreateAccounts(ids IDSource, ledger uint32, code uint16, flags uint16) error {
+	if err := models.ValidateAccountFlags(flags); err != nil {
+		return fmt.Errorf("invalid account flags: %w", err)
+	}
+	// Every account would carry the flag, so the last chain never closes.
+	linked := tbTypes.AccountFlags{Linked: true}.ToUint16()
+	if flags&linked != 0 {
+		return fmt.Errorf("invalid account flags: linked cannot be set on every account of a range")
+	}
+
+	batch := make([]models.Account, 0, batchSize)
+	var run createdRun
+	created, existing, failures := 0, 0, 0
+
+	submit := func() error {
+		var failed tigerbeetle.AccountErrors
+		err := t.client.CreateAccounts(batch)
+		if err != nil && !errors.As(err, &failed) {
+			return fmt.Errorf("error creating accounts %s..%s: %w",
+				models.FormatUint128(batch[0].ID), models.FormatUint128(batch[len(batch)-1].ID), err)
+		}
+
+		for i, account := range batch {
+			if len(failed) == 0 || failed[0].Index != i {
+				run.add(account.ID)
+				created++
+				continue
+			}
+			result := failed[0]
+			failed = failed[1:]
+
+			run.flush()
+			if result.Exists() {
+				fmt.Printf("Account %s: already exists\n", models.FormatUint128(account.ID))
+				existing++
+			} else {
+				fmt.Printf("Account %s: failed: %s\n", models.FormatUint128(account.ID), result.Result)
+				failures++
+			}
+		}
+
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		id, err := ids.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, models.Account{ID: id, Ledger: ledger, Code: code, Flags: flags})
+		if len(batch) == batchSize {
+			if err := submit(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := submit(); err != nil {
+			return err
+		}
+	}
+	run.flush()
+
+	fmt.Printf("Created %d accounts, %d already existed, %d failed\n", created, existing, failures)
+	if failures > 0 {
+		return fmt.Errorf("%d accounts failed", failures)
+	}
+	return nil
+}
+
+// GetAccounts looks up every ID from ids in batches and calls visit with each
+// ID and its balances, in input order. Accounts that do not exist are
+// visited with nil balances.
+func (t *TigerBeagle) GetAccounts(ids IDSource, visit func(id tbTypes.Uint128, ab *models.AccountBalances) error) error {
+	batch := make([]tbTypes.Uint128, 0, batchSize)
+
+	lookup := func() error {
+		balances, err := t.GetBalances(batch)
+		if err != nil {
+			return err
+		}
+		for i, id := range batch {
+			if err := visit(id, balances[i]); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		id, err := ids.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, id)
+		if len(batch) == batchSize {
+			if err := lookup(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return lookup()
+	}
+	return nil
+}
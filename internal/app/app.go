@@ -16,28 +16,72 @@ import (
 
 type TigerBeagleInterface interface {
 	ValidateConnectivity() error
+	SetIDGenerator(ids IDGenerator)
+	SetConcurrency(n int)
 	CreateAccount(id uint64, ledger uint32, code uint16, flags uint16) error
+	CreateAccounts(ids IDSource, ledger uint32, code uint16, flags uint16) error
 	GetAccount(id uint64) (*models.Account, error)
-	Transfer(debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error
-	BulkTransfer(iterations int, debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error
+	GetAccounts(ids IDSource, visit func(id tbTypes.Uint128, ab *models.AccountBalances) error) error
+	GetBalances(ids []tbTypes.Uint128) ([]*models.AccountBalances, error)
+	LookupTransfers(ids []tbTypes.Uint128) ([]*models.Transfer, error)
+	AccountHistory(filter HistoryFilter, visit func(*models.Transfer) error) error
+	BalanceHistory(filter HistoryFilter, visit func(*models.AccountBalances) error) error
+	Transfer(debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error
+	PendingTransfer(debitAccountID, creditAccountID tbTypes.Uint128, amount string, ledger uint32, code uint16, flags uint16, timeout time.Duration) error
+	PostPending(pendingID tbTypes.Uint128, amount string) error
+	VoidPending(pendingID tbTypes.Uint128) error
+	BulkTransfer(iterations int, debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error
 	GenerateAccounts(number int, ledger uint32, code uint16, flags uint16) error
 	GenerateTransfers(number int, ledger uint32, code uint16, flags uint16) error
-	MigrateAccounts(filename string) error
-	MigrateTransfers(filename string) error
+	Sweep(fromAccountID, toAccountID tbTypes.Uint128, ledger uint32, code uint16) error
+	MigrateAccounts(filename string, opts MigrateOptions) error
+	MigrateTransfers(filename string, opts MigrateOptions) error
+	BatchTransfer(filename string) error
 }
 
 var _ TigerBeagleInterface = (*TigerBeagle)(nil)
 
+// batchSize is the largest number of events per request, and of results per
+// query, as per the TigerBeetle server default.
+const batchSize = 8190
+
 type TigerBeagle struct {
-	client tigerbeetle.Client
+	client      tigerbeetle.Client
+	ids         IDGenerator
+	ledgers     models.LedgerRegistry
+	concurrency int
 }
 
 func NewTigerBeagle() *TigerBeagle {
-	return &TigerBeagle{}
+	return &TigerBeagle{ids: ULIDGenerator{}}
+}
+
+// SetIDGenerator selects the strategy used to assign transfer IDs.
+func (t *TigerBeagle) SetIDGenerator(ids IDGenerator) {
+	t.ids = ids
+}
+
+// SetLedgers sets the ledger registry used to scale decimal amounts in
+// migration files and to format amounts for display.
+func (t *TigerBeagle) SetLedgers(ledgers models.LedgerRegistry) {
+	t.ledgers = ledgers
+}
+
+// SetConcurrency sets how many batches BulkTransfer keeps in flight at once.
+// Values below one mean one.
+func (t *TigerBeagle) SetConcurrency(n int) {
+	t.concurrency = n
 }
 
-func (t *TigerBeagle) InitClient(address string) error {
-	client, err := tigerbeetle.NewClient(address)
+func (t *TigerBeagle) nextID() tbTypes.Uint128 {
+	if t.ids == nil {
+		t.ids = ULIDGenerator{}
+	}
+	return t.ids.NextID()
+}
+
+func (t *TigerBeagle) InitClient(config tigerbeetle.Config) error {
+	client, err := tigerbeetle.NewClient(config)
 	if err != nil {
 		return fmt.Errorf("error creating client: %w", err)
 	}
@@ -52,13 +96,17 @@ func (t *TigerBeagle) CloseClient() {
 }
 
 func (t *TigerBeagle) CreateAccount(id uint64, ledger uint32, code uint16, flags uint16) error {
+	if err := models.ValidateAccountFlags(flags); err != nil {
+		return fmt.Errorf("invalid account flags: %w", err)
+	}
+
 	account := models.Account{
 		ID:             tbTypes.ToUint128(id),
 		DebitsPending:  tbTypes.ToUint128(0),
 		DebitsPosted:   tbTypes.ToUint128(0),
 		CreditsPending: tbTypes.ToUint128(0),
 		CreditsPosted:  tbTypes.ToUint128(0),
-		UserID:         tbTypes.ToUint128(0),
+		UserData128:    tbTypes.ToUint128(0),
 		Ledger:         ledger,
 		Code:           code,
 		Flags:          flags,
@@ -69,7 +117,7 @@ func (t *TigerBeagle) CreateAccount(id uint64, ledger uint32, code uint16, flags
 		return fmt.Errorf("error creating account: %w", err)
 	}
 
-	fmt.Printf("Account created with ID: %d, Ledger: %d, Code: %d, Flags: %d\n", id, ledger, code, flags)
+	fmt.Printf("Account created with ID: %d, Ledger: %d, Code: %d, Flags: %s\n", id, ledger, code, strings.Join(models.AccountFlagNames(flags), ","))
 	return nil
 }
 
@@ -81,114 +129,83 @@ func (t *TigerBeagle) GetAccount(id uint64) (*models.Account, error) {
 	return account, nil
 }
 
-func (t *TigerBeagle) Transfer(debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error {
-	transfer := models.Transfer{
-		ID:              tbTypes.ToUint128(uint64(time.Now().UnixNano())),
-		DebitAccountID:  tbTypes.ToUint128(debitAccountID),
-		CreditAccountID: tbTypes.ToUint128(creditAccountID),
-		Amount:          tbTypes.ToUint128(amount),
-		Ledger:          ledger,
-		Code:            code,
-		Flags:           flags,
-	}
-
-	err := t.client.CreateTransfers([]models.Transfer{transfer})
+// GetBalances looks up the given accounts and computes their balances. The
+// result is in the order of ids, with nil entries for accounts that do not
+// exist.
+func (t *TigerBeagle) GetBalances(ids []tbTypes.Uint128) ([]*models.AccountBalances, error) {
+	accounts, err := t.client.LookupAccounts(ids)
 	if err != nil {
-		return fmt.Errorf("error creating transfer: %w", err)
+		return nil, fmt.Errorf("error fetching accounts: %w", err)
 	}
 
-	fmt.Printf("Transfer completed: %d from account %d to account %d (Ledger: %d, Code: %d, Flags: %d)\n",
-		amount, debitAccountID, creditAccountID, ledger, code, flags)
-	return nil
-}
-
-func (t *TigerBeagle) BulkTransfer(iterations int, debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error {
-	const BATCH_SIZE = 8190 // Maximum batch size as per TigerBeetle server default
-
-	// Pre-allocate all transfers
-	allTransfers := make([]models.Transfer, iterations)
-	for i := 0; i < iterations; i++ {
-		allTransfers[i] = models.Transfer{
-			ID:              tbTypes.ToUint128(uint64(time.Now().UnixNano()) + uint64(i)),
-			DebitAccountID:  tbTypes.ToUint128(debitAccountID),
-			CreditAccountID: tbTypes.ToUint128(creditAccountID),
-			Amount:          tbTypes.ToUint128(amount),
-			Ledger:          ledger,
-			Code:            code,
-			Flags:           flags,
-		}
+	byID := make(map[tbTypes.Uint128]models.Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
 	}
 
-	// Process transfers in batches
-	for i := 0; i < len(allTransfers); i += BATCH_SIZE {
-		end := i + BATCH_SIZE
-		if end > len(allTransfers) {
-			end = len(allTransfers)
-		}
-		batch := allTransfers[i:end]
-
-		err := t.client.CreateTransfers(batch)
-		if err != nil {
-			return fmt.Errorf("error creating transfers in batch %d-%d: %w", i, end-1, err)
+	balances := make([]*models.AccountBalances, len(ids))
+	for i, id := range ids {
+		if account, ok := byID[id]; ok {
+			balances[i] = models.NewAccountBalances(account)
 		}
-
-		fmt.Printf("Processed transfers %d-%d\n", i, end-1)
 	}
-
-	fmt.Printf("All %d transfers completed successfully\n", iterations)
-	return nil
+	return balances, nil
 }
 
-func (t *TigerBeagle) MigrateAccounts(filename string) error {
-	const BATCH_SIZE = 8190 // Maximum batch size as per TigerBeetle server default
-
-	data, err := os.ReadFile(filename)
+// LookupTransfers fetches the given transfers. The result is in the order of
+// ids, with nil entries for transfers that do not exist.
+func (t *TigerBeagle) LookupTransfers(ids []tbTypes.Uint128) ([]*models.Transfer, error) {
+	found, err := t.client.LookupTransfers(ids)
 	if err != nil {
-		return fmt.Errorf("error reading file: %w", err)
+		return nil, fmt.Errorf("error fetching transfers: %w", err)
 	}
 
-	var accounts []models.Account
-	if err := json.Unmarshal(data, &accounts); err != nil {
-		return fmt.Errorf("error parsing JSON: %w", err)
+	byID := make(map[tbTypes.Uint128]*models.Transfer, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
 	}
 
-	// Process accounts in batches
-	for i := 0; i < len(accounts); i += BATCH_SIZE {
-		end := i + BATCH_SIZE
-		if end > len(accounts) {
-			end = len(accounts)
-		}
-		batch := accounts[i:end]
-
-		err = t.client.CreateAccounts(batch)
-		if err != nil {
-			return fmt.Errorf("error creating accounts in batch %d-%d: %w", i, end-1, err)
-		}
-
-		fmt.Printf("Processed accounts %d-%d\n", i, end-1)
+	transfers := make([]*models.Transfer, len(ids))
+	for i, id := range ids {
+		transfers[i] = byID[id]
 	}
-
-	fmt.Printf("Successfully migrated all %d accounts\n", len(accounts))
-	return nil
+	return transfers, nil
 }
 
-func (t *TigerBeagle) MigrateTransfers(filename string) error {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("error reading file: %w", err)
+func (t *TigerBeagle) Transfer(debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error {
+	if err := models.ValidateTransferFlags(flags); err != nil {
+		return fmt.Errorf("invalid transfer flags: %w", err)
 	}
 
-	var transfers []models.Transfer
-	if err := json.Unmarshal(data, &transfers); err != nil {
-		return fmt.Errorf("error parsing JSON: %w", err)
+	transfer := models.Transfer{
+		ID:              t.nextID(),
+		DebitAccountID:  debitAccountID,
+		CreditAccountID: creditAccountID,
+		Amount:          tbTypes.ToUint128(amount),
+		Ledger:          ledger,
+		Code:            code,
+		Flags:           flags,
 	}
 
-	err = t.client.CreateTransfers(transfers)
+	err := t.client.CreateTransfers([]models.Transfer{transfer})
 	if err != nil {
-		return fmt.Errorf("error creating transfers: %w", err)
+		return fmt.Errorf("error creating transfer: %w", err)
+	}
+
+	if isBalancing(flags) {
+		moved, err := t.movedAmount(transfer.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Transfer %s completed: %s of at most %s from account %s to account %s (Ledger: %d, Code: %d, Flags: %s)\n",
+			models.FormatUint128(transfer.ID), t.ledgers.FormatUint128Amount(moved, ledger), t.ledgers.FormatUint128Amount(transfer.Amount, ledger),
+			models.FormatUint128(debitAccountID), models.FormatUint128(creditAccountID), ledger, code, strings.Join(models.TransferFlagNames(flags), ","))
+		return nil
 	}
 
-	fmt.Printf("Successfully migrated %d transfers\n", len(transfers))
+	fmt.Printf("Transfer %s completed: %s from account %s to account %s (Ledger: %d, Code: %d, Flags: %s)\n",
+		models.FormatUint128(transfer.ID), t.ledgers.FormatUint128Amount(transfer.Amount, ledger),
+		models.FormatUint128(debitAccountID), models.FormatUint128(creditAccountID), ledger, code, strings.Join(models.TransferFlagNames(flags), ","))
 	return nil
 }
 
@@ -209,10 +226,15 @@ func (t *TigerBeagle) ValidateConnectivity() error {
 }
 
 func (t *TigerBeagle) GenerateAccounts(number int, ledger uint32, code uint16, flags uint16) error {
+	return generateAccounts(number, ledger, code, flags, "generated_accounts.json")
+}
+
+// generateAccounts writes number accounts to filename.
+func generateAccounts(number int, ledger uint32, code uint16, flags uint16, filename string) error {
 	accounts := make([]models.Account, number)
 	for i := 0; i < number; i++ {
 		account := models.Account{
-			UserID:         types.ToUint128(0),
+			UserData128:    types.ToUint128(0),
 			Ledger:         ledger,
 			Code:           code,
 			Flags:          flags,
@@ -225,10 +247,16 @@ func (t *TigerBeagle) GenerateAccounts(number int, ledger uint32, code uint16, f
 		accounts[i] = account
 	}
 
-	return writeJSONToFile(accounts, "generated_accounts.json")
+	return writeJSONToFile(accounts, filename)
 }
 
 func (t *TigerBeagle) GenerateTransfers(number int, ledger uint32, code uint16, flags uint16) error {
+	return generateTransfers(number, ledger, code, flags, "generated_transfers.json")
+}
+
+// generateTransfers writes number transfers between random generated
+// accounts to filename.
+func generateTransfers(number int, ledger uint32, code uint16, flags uint16, filename string) error {
 	transfers := make([]models.Transfer, number)
 	rand.Seed(time.Now().UnixNano())
 
@@ -244,7 +272,7 @@ func (t *TigerBeagle) GenerateTransfers(number int, ledger uint32, code uint16,
 		}
 	}
 
-	return writeJSONToFile(transfers, "generated_transfers.json")
+	return writeJSONToFile(transfers, filename)
 }
 
 func writeJSONToFile(data interface{}, filename string) error {
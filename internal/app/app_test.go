@@ -1,12 +1,20 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
 	"github.com/kris-hansen/tigerbeagle/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
 // Mock tigerbeetle.Client
@@ -24,11 +32,31 @@ func (m *MockClient) LookupAccount(id uint64) (*models.Account, error) {
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
+func (m *MockClient) LookupAccounts(ids []tbTypes.Uint128) ([]models.Account, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]models.Account), args.Error(1)
+}
+
 func (m *MockClient) CreateTransfers(transfers []models.Transfer) error {
 	args := m.Called(transfers)
 	return args.Error(0)
 }
 
+func (m *MockClient) LookupTransfers(ids []tbTypes.Uint128) ([]models.Transfer, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]models.Transfer), args.Error(1)
+}
+
+func (m *MockClient) GetAccountTransfers(filter tbTypes.AccountFilter) ([]models.Transfer, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Transfer), args.Error(1)
+}
+
+func (m *MockClient) GetAccountBalances(filter tbTypes.AccountFilter) ([]tbTypes.AccountBalance, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]tbTypes.AccountBalance), args.Error(1)
+}
+
 func (m *MockClient) Ping() error {
 	args := m.Called()
 	return args.Error(0)
@@ -99,16 +127,26 @@ func TestTransfer(t *testing.T) {
 
 	// Test successful transfer
 	mockClient.On("CreateTransfers", mock.Anything).Return(nil).Once()
-	err := tb.Transfer(1, 2, 100, 700, 10, 0)
+	err := tb.Transfer(tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 0)
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 
 	// Test failed transfer
 	mockClient.On("CreateTransfers", mock.Anything).Return(fmt.Errorf("transfer failed")).Once()
-	err = tb.Transfer(3, 4, 200, 700, 10, 0)
+	err = tb.Transfer(tbTypes.ToUint128(3), tbTypes.ToUint128(4), 200, 700, 10, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "transfer failed")
 	mockClient.AssertExpectations(t)
+
+	// Test accounts wider than 64 bits reach the transfer unchanged
+	wide, err := models.ParseUint128("0x10000000000000001")
+	assert.NoError(t, err)
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 1 && transfers[0].DebitAccountID == wide && transfers[0].CreditAccountID == tbTypes.ToUint128(2)
+	})).Return(nil).Once()
+	err = tb.Transfer(wide, tbTypes.ToUint128(2), 100, 700, 10, 0)
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
 }
 
 func TestBulkTransfer(t *testing.T) {
@@ -117,13 +155,13 @@ func TestBulkTransfer(t *testing.T) {
 
 	// Test successful bulk transfer
 	mockClient.On("CreateTransfers", mock.AnythingOfType("[]models.Transfer")).Return(nil).Once()
-	err := tb.BulkTransfer(3, 1, 2, 100, 700, 10, 0)
+	err := tb.BulkTransfer(3, tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 0)
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 
 	// Test failed bulk transfer
 	mockClient.On("CreateTransfers", mock.AnythingOfType("[]models.Transfer")).Return(fmt.Errorf("bulk transfer failed")).Once()
-	err = tb.BulkTransfer(2, 3, 4, 200, 700, 10, 0)
+	err = tb.BulkTransfer(2, tbTypes.ToUint128(3), tbTypes.ToUint128(4), 200, 700, 10, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "bulk transfer failed")
 	mockClient.AssertExpectations(t)
@@ -132,23 +170,939 @@ func TestBulkTransfer(t *testing.T) {
 	const BATCH_SIZE = 8189
 	largeIterations := BATCH_SIZE + 10
 	mockClient.On("CreateTransfers", mock.AnythingOfType("[]models.Transfer")).Return(nil).Times(2)
-	err = tb.BulkTransfer(largeIterations, 5, 6, 300, 700, 10, 0)
+	err = tb.BulkTransfer(largeIterations, tbTypes.ToUint128(5), tbTypes.ToUint128(6), 300, 700, 10, 0)
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 }
 
 func TestGenerateAccounts(t *testing.T) {
-	tb := &TigerBeagle{}
+	filename := filepath.Join(t.TempDir(), "generated_accounts.json")
 
-	err := tb.GenerateAccounts(5, 700, 10, 0)
+	err := generateAccounts(5, 700, 10, 0, filename)
 	assert.NoError(t, err)
-
+	assert.FileExists(t, filename)
 }
 
 func TestGenerateTransfers(t *testing.T) {
-	tb := &TigerBeagle{}
+	filename := filepath.Join(t.TempDir(), "generated_transfers.json")
+
+	err := generateTransfers(5, 700, 10, 0, filename)
+	assert.NoError(t, err)
+	assert.FileExists(t, filename)
+}
 
-	err := tb.GenerateTransfers(5, 700, 10, 0)
+func TestMigrateTransfersReadsGeneratedFile(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "generated_transfers.json")
+	err := generateTransfers(5, 700, 10, 0, filename)
 	assert.NoError(t, err)
 
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 5 && transfers[0].Ledger == 700 && transfers[0].Code == 10
+	})).Return(nil).Once()
+	err = tb.MigrateTransfers(filename, MigrateOptions{})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestInvalidFlagsAreRejectedBeforeSubmission(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	err := tb.CreateAccount(1, 700, 10, 6)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid account flags")
+
+	err = tb.Transfer(tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 12)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transfer flags")
+
+	mockClient.AssertNotCalled(t, "CreateAccounts", mock.Anything)
+	mockClient.AssertNotCalled(t, "CreateTransfers", mock.Anything)
+}
+
+func TestGetBalances(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(1), tbTypes.ToUint128(2)}
+	mockClient.On("LookupAccounts", ids).Return([]models.Account{{
+		ID:             tbTypes.ToUint128(1),
+		DebitsPosted:   tbTypes.ToUint128(30),
+		DebitsPending:  tbTypes.ToUint128(5),
+		CreditsPosted:  tbTypes.ToUint128(100),
+		CreditsPending: tbTypes.ToUint128(20),
+	}}, nil).Once()
+
+	balances, err := tb.GetBalances(ids)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, "70", balances[0].Balance.String())
+	assert.Equal(t, "15", balances[0].Pending.String())
+	assert.Equal(t, "65", balances[0].Available.String())
+	assert.Nil(t, balances[1])
+	mockClient.AssertExpectations(t)
+}
+
+func TestIDGenerators(t *testing.T) {
+	// ULIDs are unique and increase monotonically
+	ulids := ULIDGenerator{}
+	first, second := ulids.NextID(), ulids.NextID()
+	firstInt, secondInt := first.BigInt(), second.BigInt()
+	assert.Equal(t, -1, firstInt.Cmp(&secondInt))
+
+	// Sequential IDs count up from the caller-supplied start
+	sequential, err := NewSequentialIDGenerator(tbTypes.ToUint128(41))
+	assert.NoError(t, err)
+	assert.Equal(t, tbTypes.ToUint128(41), sequential.NextID())
+	assert.Equal(t, tbTypes.ToUint128(42), sequential.NextID())
+
+	_, err = NewSequentialIDGenerator(tbTypes.ToUint128(0))
+	assert.Error(t, err)
+
+	// Reference IDs are deterministic across generators
+	a, err := NewReferenceIDGenerator("invoice-123")
+	assert.NoError(t, err)
+	b, _ := NewReferenceIDGenerator("invoice-123")
+	c, _ := NewReferenceIDGenerator("invoice-124")
+	idA := a.NextID()
+	assert.Equal(t, idA, b.NextID())
+	assert.NotEqual(t, idA, c.NextID())
+	assert.NotEqual(t, idA, a.NextID())
+}
+
+func TestBulkTransferUsesIDGenerator(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	start := tbTypes.ToUint128(1000)
+	ids, err := NewSequentialIDGenerator(start)
+	assert.NoError(t, err)
+	tb.SetIDGenerator(ids)
+
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 3 &&
+			transfers[0].ID == tbTypes.ToUint128(1000) &&
+			transfers[2].ID == tbTypes.ToUint128(1002)
+	})).Return(nil).Once()
+
+	err = tb.BulkTransfer(3, tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 0)
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestMigrateAccountsReportsFailedRows(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "accounts.json")
+	data := `[{"id": 1, "ledger": 700, "code": 10}, {"id": 2, "ledger": 700, "code": 10}, {"id": 3, "ledger": 0, "code": 10}]`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	mockClient.On("CreateAccounts", mock.Anything).Return(tigerbeetle.AccountErrors{
+		{Index: 0, ID: tbTypes.ToUint128(1), Result: tbTypes.AccountExists},
+		{Index: 2, ID: tbTypes.ToUint128(3), Result: tbTypes.AccountLedgerMustNotBeZero},
+	}).Once()
+
+	err := tb.MigrateAccounts(filename, MigrateOptions{})
+	assert.Error(t, err)
+
+	var failed tigerbeetle.AccountErrors
+	assert.True(t, errors.As(err, &failed))
+	assert.Len(t, failed, 2)
+	assert.Equal(t, 2, failed[1].Index)
+	assert.Equal(t, tbTypes.AccountLedgerMustNotBeZero, failed[1].Result)
+	mockClient.AssertExpectations(t)
+}
+
+func TestMigrateAccountsIdempotent(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "accounts.json")
+	data := `[{"id": 1, "ledger": 700, "code": 10}, {"id": 2, "ledger": 700, "code": 10}, {"id": 3, "ledger": 700, "code": 10}]`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	// Re-run where every record was applied before
+	mockClient.On("CreateAccounts", mock.Anything).Return(tigerbeetle.AccountErrors{
+		{Index: 0, ID: tbTypes.ToUint128(1), Result: tbTypes.AccountExists},
+		{Index: 1, ID: tbTypes.ToUint128(2), Result: tbTypes.AccountExists},
+	}).Once()
+	err := tb.MigrateAccounts(filename, MigrateOptions{Idempotent: true})
+	assert.NoError(t, err)
+
+	// Re-run where one record exists with different fields
+	mockClient.On("CreateAccounts", mock.Anything).Return(tigerbeetle.AccountErrors{
+		{Index: 0, ID: tbTypes.ToUint128(1), Result: tbTypes.AccountExists},
+		{Index: 2, ID: tbTypes.ToUint128(3), Result: tbTypes.AccountExistsWithDifferentLedger},
+	}).Once()
+	err = tb.MigrateAccounts(filename, MigrateOptions{Idempotent: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "1 conflicts and 0 failures")
+	mockClient.AssertExpectations(t)
+}
+
+func TestMigrateTransfersIdempotentAppliedChain(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "transfers.ndjson")
+	data := `{"id": 1, "debit_account_id": 1, "credit_account_id": 2, "amount": 10, "ledger": 700, "code": 10, "flags": "linked"}
+{"id": 2, "debit_account_id": 2, "credit_account_id": 3, "amount": 10, "ledger": 700, "code": 10, "flags": "linked"}
+{"id": 3, "debit_account_id": 3, "credit_account_id": 1, "amount": 10, "ledger": 700, "code": 10}
+{"id": 4, "debit_account_id": 1, "credit_account_id": 2, "amount": 10, "ledger": 700, "code": 10}
+`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	// Re-running the chain fails its first transfer with exists and the
+	// rest with linked_event_failed.
+	mockClient.On("CreateTransfers", mock.Anything).Return(tigerbeetle.TransferErrors{
+		{Index: 0, ID: tbTypes.ToUint128(1), Result: tbTypes.TransferExists},
+		{Index: 1, ID: tbTypes.ToUint128(2), Result: tbTypes.TransferLinkedEventFailed},
+		{Index: 2, ID: tbTypes.ToUint128(3), Result: tbTypes.TransferLinkedEventFailed},
+		{Index: 3, ID: tbTypes.ToUint128(4), Result: tbTypes.TransferExists},
+	}).Once()
+	err := tb.MigrateTransfers(filename, MigrateOptions{Idempotent: true})
+	assert.NoError(t, err)
+
+	// A chain that failed on anything else is still reported.
+	mockClient.On("CreateTransfers", mock.Anything).Return(tigerbeetle.TransferErrors{
+		{Index: 0, ID: tbTypes.ToUint128(1), Result: tbTypes.TransferExists},
+		{Index: 1, ID: tbTypes.ToUint128(2), Result: tbTypes.TransferExceedsCredits},
+		{Index: 2, ID: tbTypes.ToUint128(3), Result: tbTypes.TransferLinkedEventFailed},
+	}).Once()
+	err = tb.MigrateTransfers(filename, MigrateOptions{Idempotent: true})
+	assert.EqualError(t, err, "transfer migration finished with 0 conflicts and 2 failures")
+	mockClient.AssertExpectations(t)
+}
+
+func TestMigrationSummary(t *testing.T) {
+	summary := &migrationSummary{kind: "transfer"}
+	summary.add(5, transferEventResults(tigerbeetle.TransferErrors{
+		{Index: 0, Result: tbTypes.TransferExists},
+		{Index: 1, Result: tbTypes.TransferExistsWithDifferentAmount},
+		{Index: 2, Result: tbTypes.TransferExceedsCredits},
+	}, nil))
+
+	assert.Equal(t, 2, summary.created)
+	assert.Equal(t, 1, summary.existing)
+	assert.Len(t, summary.conflicts, 1)
+	assert.Len(t, summary.failures, 1)
+	assert.Error(t, summary.err())
+}
+
+func TestMigrateAccountsStreamsNDJSON(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "accounts.ndjson")
+	data := "{\"id\": 1, \"ledger\": 700, \"code\": 10}\n{\"id\": 2, \"ledger\": 700, \"code\": 10}\n"
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		return len(accounts) == 2 && accounts[1].ID == tbTypes.ToUint128(2)
+	})).Return(nil).Once()
+
+	err := tb.MigrateAccounts(filename, MigrateOptions{})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestLookupTransfers(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(7), tbTypes.ToUint128(8)}
+	mockClient.On("LookupTransfers", ids).Return([]models.Transfer{
+		{ID: tbTypes.ToUint128(8), Amount: tbTypes.ToUint128(100), PendingID: tbTypes.ToUint128(3)},
+	}, nil).Once()
+
+	transfers, err := tb.LookupTransfers(ids)
+	assert.NoError(t, err)
+	assert.Len(t, transfers, 2)
+	assert.Nil(t, transfers[0])
+	assert.Equal(t, tbTypes.ToUint128(3), transfers[1].PendingID)
+	mockClient.AssertExpectations(t)
+}
+
+func TestAccountHistoryPages(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	// A full first page forces a second query starting after its last timestamp.
+	first := make([]models.Transfer, batchSize)
+	for i := range first {
+		first[i] = models.Transfer{ID: tbTypes.ToUint128(uint64(i + 1)), Timestamp: uint64(100 + i)}
+	}
+	last := first[len(first)-1].Timestamp
+
+	mockClient.On("GetAccountTransfers", tbTypes.AccountFilter{
+		AccountID:    tbTypes.ToUint128(1),
+		TimestampMin: 100,
+		Limit:        batchSize,
+		Flags:        tbTypes.AccountFilterFlags{Debits: true, Credits: true}.ToUint32(),
+	}).Return(first, nil).Once()
+	mockClient.On("GetAccountTransfers", tbTypes.AccountFilter{
+		AccountID:    tbTypes.ToUint128(1),
+		TimestampMin: last + 1,
+		Limit:        10,
+		Flags:        tbTypes.AccountFilterFlags{Debits: true, Credits: true}.ToUint32(),
+	}).Return([]models.Transfer{{ID: tbTypes.ToUint128(9000), Timestamp: last + 5}}, nil).Once()
+
+	count := 0
+	err := tb.AccountHistory(HistoryFilter{AccountID: tbTypes.ToUint128(1), Since: 100, Limit: 8200}, func(*models.Transfer) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 8191, count)
+	mockClient.AssertExpectations(t)
+}
+
+func TestBalanceHistory(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	history := tbTypes.AccountFlags{History: true}.ToUint16()
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(1)}).Return([]models.Account{
+		{ID: tbTypes.ToUint128(1), Ledger: 700, Flags: history},
+	}, nil).Once()
+	mockClient.On("GetAccountBalances", mock.Anything).Return([]tbTypes.AccountBalance{
+		{CreditsPosted: tbTypes.ToUint128(100), Timestamp: 10},
+		{CreditsPosted: tbTypes.ToUint128(100), DebitsPosted: tbTypes.ToUint128(30), Timestamp: 20},
+	}, nil).Once()
+
+	var balances []string
+	err := tb.BalanceHistory(HistoryFilter{AccountID: tbTypes.ToUint128(1)}, func(ab *models.AccountBalances) error {
+		assert.Equal(t, uint32(700), ab.Ledger)
+		balances = append(balances, ab.Balance.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"100", "70"}, balances)
+
+	// Accounts without the history flag have no snapshots to read.
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(2)}).Return([]models.Account{
+		{ID: tbTypes.ToUint128(2)},
+	}, nil).Once()
+	err = tb.BalanceHistory(HistoryFilter{AccountID: tbTypes.ToUint128(2)}, func(*models.AccountBalances) error { return nil })
+	assert.ErrorContains(t, err, "history flag")
+	mockClient.AssertExpectations(t)
+}
+
+func TestPendingTransfers(t *testing.T) {
+	mockClient := new(MockClient)
+	ids, err := NewSequentialIDGenerator(tbTypes.ToUint128(100))
+	assert.NoError(t, err)
+	tb := &TigerBeagle{client: mockClient, ids: ids}
+
+	pendingFlag := tbTypes.TransferFlags{Pending: true}.ToUint16()
+	mockClient.On("CreateTransfers", []models.Transfer{{
+		ID:              tbTypes.ToUint128(100),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          tbTypes.ToUint128(500),
+		Timeout:         30,
+		Ledger:          700,
+		Code:            10,
+		Flags:           pendingFlag,
+	}}).Return(nil).Once()
+	assert.NoError(t, tb.PendingTransfer(tbTypes.ToUint128(1), tbTypes.ToUint128(2), "500", 700, 10, 0, 30*time.Second))
+
+	assert.Error(t, tb.PendingTransfer(tbTypes.ToUint128(1), tbTypes.ToUint128(2), "500", 700, 10, 0, 1500*time.Millisecond))
+
+	pending := models.Transfer{
+		ID:              tbTypes.ToUint128(100),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          tbTypes.ToUint128(500),
+		Ledger:          700,
+		Code:            10,
+		Flags:           pendingFlag,
+	}
+	mockClient.On("LookupTransfers", []tbTypes.Uint128{tbTypes.ToUint128(100)}).Return([]models.Transfer{pending}, nil)
+
+	// A partial post sends the posted amount and leaves the rest to be released.
+	mockClient.On("CreateTransfers", []models.Transfer{{
+		ID:              tbTypes.ToUint128(101),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          tbTypes.ToUint128(200),
+		PendingID:       tbTypes.ToUint128(100),
+		Ledger:          700,
+		Code:            10,
+		Flags:           tbTypes.TransferFlags{PostPendingTransfer: true}.ToUint16(),
+	}}).Return(nil).Once()
+	assert.NoError(t, tb.PostPending(tbTypes.ToUint128(100), "200"))
+
+	assert.ErrorContains(t, tb.PostPending(tbTypes.ToUint128(100), "501"), "exceeds the reserved")
+
+	mockClient.On("CreateTransfers", []models.Transfer{{
+		ID:              tbTypes.ToUint128(102),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          tbTypes.ToUint128(500),
+		PendingID:       tbTypes.ToUint128(100),
+		Ledger:          700,
+		Code:            10,
+		Flags:           tbTypes.TransferFlags{VoidPendingTransfer: true}.ToUint16(),
+	}}).Return(nil).Once()
+	assert.NoError(t, tb.VoidPending(tbTypes.ToUint128(100)))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestPendingTransferParsesLedgerAmount(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient, ledgers: models.LedgerRegistry{700: {Scale: 2}}}
+
+	wide, err := models.ParseUint128("0x10000000000000001")
+	assert.NoError(t, err)
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 1 && transfers[0].DebitAccountID == wide && transfers[0].Amount == tbTypes.ToUint128(150)
+	})).Return(nil).Once()
+	assert.NoError(t, tb.PendingTransfer(wide, tbTypes.ToUint128(2), "1.50", 700, 10, 0, 0))
+
+	assert.ErrorContains(t, tb.PendingTransfer(wide, tbTypes.ToUint128(2), "1.50", 710, 10, 0, 0), "invalid amount")
+	mockClient.AssertExpectations(t)
+}
+
+func TestBatchTransferLinksChains(t *testing.T) {
+	mockClient := new(MockClient)
+	ids, err := NewSequentialIDGenerator(tbTypes.ToUint128(50))
+	assert.NoError(t, err)
+	tb := &TigerBeagle{client: mockClient, ids: ids}
+
+	filename := filepath.Join(t.TempDir(), "chains.json")
+	data := `{"id": 1, "debit_account_id": 1, "credit_account_id": 2, "amount": 10, "ledger": 700, "code": 10}
+{"chain": [{"debit_account_id": 1, "credit_account_id": 2, "amount": 20, "ledger": 700, "code": 10}, {"id": 3, "debit_account_id": 2, "credit_account_id": 3, "amount": 20, "ledger": 700, "code": 10}]}
+{"id": 4, "debit_account_id": 1, "credit_account_id": 2, "amount": 30, "ledger": 700, "code": 10}
+`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	linked := tbTypes.TransferFlags{Linked: true}.ToUint16()
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(batch []models.Transfer) bool {
+		return len(batch) == 4 &&
+			batch[0].Flags == 0 &&
+			batch[1].ID == tbTypes.ToUint128(50) && batch[1].Flags == linked &&
+			batch[2].Flags == 0 &&
+			batch[3].Flags == 0
+	})).Return(tigerbeetle.TransferErrors{
+		{Index: 1, ID: tbTypes.ToUint128(50), Result: tbTypes.TransferLinkedEventFailed},
+		{Index: 2, ID: tbTypes.ToUint128(3), Result: tbTypes.TransferExceedsCredits},
+	}).Once()
+
+	err = tb.BatchTransfer(filename)
+	assert.EqualError(t, err, "1 of 3 chains failed")
+	mockClient.AssertExpectations(t)
+}
+
+func TestChainBoundary(t *testing.T) {
+	linked := tbTypes.TransferFlags{Linked: true}.ToUint16()
+
+	boundary := func(transfers []models.Transfer) int {
+		return chainBoundary(len(transfers), func(i int) bool { return transfers[i].Flags&linked != 0 })
+	}
+	assert.Equal(t, 3, boundary([]models.Transfer{{Flags: linked}, {}, {}}))
+	assert.Equal(t, 2, boundary([]models.Transfer{{Flags: linked}, {}, {Flags: linked}}))
+	assert.Equal(t, 0, boundary([]models.Transfer{{Flags: linked}, {Flags: linked}}))
+}
+
+func TestSweepLooksUpMovedAmount(t *testing.T) {
+	mockClient := new(MockClient)
+	ids, err := NewSequentialIDGenerator(tbTypes.ToUint128(70))
+	assert.NoError(t, err)
+	tb := &TigerBeagle{client: mockClient, ids: ids}
+
+	mockClient.On("CreateTransfers", []models.Transfer{{
+		ID:              tbTypes.ToUint128(70),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          maxAmount,
+		Ledger:          700,
+		Code:            10,
+		Flags:           tbTypes.TransferFlags{BalancingDebit: true}.ToUint16(),
+	}}).Return(nil).Once()
+	mockClient.On("LookupTransfers", []tbTypes.Uint128{tbTypes.ToUint128(70)}).Return([]models.Transfer{
+		{ID: tbTypes.ToUint128(70), Amount: tbTypes.ToUint128(1234)},
+	}, nil).Once()
+
+	assert.NoError(t, tb.Sweep(tbTypes.ToUint128(1), tbTypes.ToUint128(2), 700, 10))
+
+	// Balancing transfers report the moved amount too.
+	balancingCredit := tbTypes.TransferFlags{BalancingCredit: true}.ToUint16()
+	mockClient.On("CreateTransfers", mock.Anything).Return(nil).Once()
+	mockClient.On("LookupTransfers", []tbTypes.Uint128{tbTypes.ToUint128(71)}).Return([]models.Transfer{}, nil).Once()
+	assert.ErrorContains(t, tb.Transfer(tbTypes.ToUint128(1), tbTypes.ToUint128(2), 500, 700, 10, balancingCredit), "not found after creation")
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBulkTransferConcurrent(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+	tb.SetConcurrency(4)
+
+	// Every batch is submitted exactly once across the workers.
+	mockClient.On("CreateTransfers", mock.AnythingOfType("[]models.Transfer")).Return(nil).Times(5)
+	err := tb.BulkTransfer(4*batchSize+1, tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 0)
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestBulkTransferCancelsOnFailure(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+	tb.SetConcurrency(2)
+
+	mockClient.On("CreateTransfers", mock.AnythingOfType("[]models.Transfer")).Return(fmt.Errorf("cluster unavailable"))
+	err := tb.BulkTransfer(100*batchSize, tbTypes.ToUint128(1), tbTypes.ToUint128(2), 100, 700, 10, 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster unavailable")
+
+	// At most one batch per worker is in flight when the first one fails.
+	assert.LessOrEqual(t, len(mockClient.Calls), 2)
+}
+
+func TestIDSources(t *testing.T) {
+	collect := func(src IDSource) []tbTypes.Uint128 {
+		var ids []tbTypes.Uint128
+		for {
+			id, err := src.Next()
+			if err == io.EOF {
+				return ids
+			}
+			assert.NoError(t, err)
+			ids = append(ids, id)
+		}
+	}
+
+	r, err := ParseIDRange("5..7")
+	assert.NoError(t, err)
+	single, err := ParseIDRange("0x10")
+	assert.NoError(t, err)
+	assert.Equal(t, []tbTypes.Uint128{
+		tbTypes.ToUint128(5), tbTypes.ToUint128(6), tbTypes.ToUint128(7), tbTypes.ToUint128(16),
+	}, collect(NewRangeSource([]IDRange{r, single})))
+
+	_, err = ParseIDRange("7..5")
+	assert.Error(t, err)
+
+	list := NewListSource(strings.NewReader("# setup\n1\n\n3..4\n"))
+	assert.Equal(t, []tbTypes.Uint128{
+		tbTypes.ToUint128(1), tbTypes.ToUint128(3), tbTypes.ToUint128(4),
+	}, collect(list))
+
+	bad := NewListSource(strings.NewReader("1\nabc\n"))
+	_, err = bad.Next()
+	assert.NoError(t, err)
+	_, err = bad.Next()
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestCreateAccountsReportsEachID(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		return len(accounts) == 4 && accounts[0].ID == tbTypes.ToUint128(100) && accounts[3].Ledger == 700
+	})).Return(tigerbeetle.AccountErrors{
+		{Index: 1, ID: tbTypes.ToUint128(101), Result: tbTypes.AccountExists},
+		{Index: 2, ID: tbTypes.ToUint128(102), Result: tbTypes.AccountExistsWithDifferentLedger},
+	}).Once()
+
+	err := tb.CreateAccounts(NewRangeSource([]IDRange{{First: tbTypes.ToUint128(100), Last: tbTypes.ToUint128(103)}}), 700, 10, 0)
+	assert.EqualError(t, err, "1 accounts failed")
+	mockClient.AssertExpectations(t)
+
+	linked := tbTypes.AccountFlags{Linked: true}.ToUint16()
+	err = tb.CreateAccounts(NewRangeSource([]IDRange{{First: tbTypes.ToUint128(200), Last: tbTypes.ToUint128(203)}}), 700, 10, linked)
+	assert.ErrorContains(t, err, "linked cannot be set")
+	mockClient.AssertNumberOfCalls(t, "CreateAccounts", 1)
+}
+
+func TestGetAccountsVisitsInInputOrder(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(1), tbTypes.ToUint128(2), tbTypes.ToUint128(3)}
+	mockClient.On("LookupAccounts", ids).Return([]models.Account{{ID: tbTypes.ToUint128(2), Ledger: 700}}, nil).Once()
+
+	var found []bool
+	err := tb.GetAccounts(NewRangeSource([]IDRange{{First: ids[0], Last: ids[2]}}), func(id tbTypes.Uint128, ab *models.AccountBalances) error {
+		found = append(found, ab != nil)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, false}, found)
+	mockClient.AssertExpectations(t)
+}
+
+func TestMigrateTransfersFromCSVSkipsBadRows(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	filename := filepath.Join(t.TempDir(), "transfers.csv")
+	data := "ref,from,to,amount\n1,1001,1002,1.00\n2,1001,1002,oops\n3,1001,1002,3.00\n"
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	mapping := &models.CSVMapping{
+		Columns:  map[string]string{"id": "ref", "debit_account_id": "from", "credit_account_id": "to", "amount": "amount * 100"},
+		Defaults: map[string]string{"ledger": "700", "code": "10"},
+	}
+
+	// The second transfer in the batch is the third row of the input.
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 2 && transfers[1].Amount == tbTypes.ToUint128(300)
+	})).Return(tigerbeetle.TransferErrors{
+		{Index: 1, ID: tbTypes.ToUint128(3), Result: tbTypes.TransferExceedsCredits},
+	}).Once()
+
+	err := tb.MigrateTransfers(filename, MigrateOptions{Idempotent: true, Mapping: mapping})
+	assert.EqualError(t, err, "transfer migration finished with 0 conflicts, 1 failures and 1 invalid rows")
+	mockClient.AssertExpectations(t)
+
+	// Without --idempotent the first invalid row stops the migration.
+	err = tb.MigrateTransfers(filename, MigrateOptions{Mapping: mapping})
+	assert.ErrorContains(t, err, "error parsing CSV: line 3")
+}
+
+func TestMigrateAccountsKeepsChainsInOneBatch(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	// The chain starts two records before the batch is full, so it is held
+	// back whole for the next request.
+	var data strings.Builder
+	for i := 1; i < batchSize-1; i++ {
+		fmt.Fprintf(&data, "{\"id\": %d, \"ledger\": 700, \"code\": 10}\n", i)
+	}
+	data.WriteString(`{"id": 9001, "ledger": 700, "code": 10, "flags": "linked"}
+{"id": 9002, "ledger": 700, "code": 10, "flags": "linked"}
+{"id": 9003, "ledger": 700, "code": 10}
+`)
+	filename := filepath.Join(t.TempDir(), "accounts.ndjson")
+	assert.NoError(t, os.WriteFile(filename, []byte(data.String()), 0o644))
+
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		return len(accounts) == batchSize-2
+	})).Return(nil).Once()
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		return len(accounts) == 3 && accounts[0].ID == tbTypes.ToUint128(9001)
+	})).Return(nil).Once()
+
+	err := tb.MigrateAccounts(filename, MigrateOptions{})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+
+	// The dry run batches the same way, so the chain is not reported open.
+	assert.NoError(t, tb.MigrateAccounts(filename, MigrateOptions{DryRun: true}))
+}
+
+func TestMigrateAccountsResumesFromCheckpoint(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "accounts.json")
+	data := `[{"id": 1, "ledger": 700, "code": 10}, {"id": 2, "ledger": 700, "code": 10}]`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	// A failed batch leaves no checkpoint behind.
+	mockClient.On("CreateAccounts", mock.Anything).Return(errors.New("connection lost")).Once()
+	err := tb.MigrateAccounts(filename, MigrateOptions{})
+	assert.Error(t, err)
+	_, err = os.Stat(filename + ".checkpoint")
+	assert.True(t, os.IsNotExist(err))
+
+	// Record the first account as committed.
+	file, _, err := openMigrationFile(filename)
+	assert.NoError(t, err)
+	hash, err := hashFile(file)
+	file.Close()
+	assert.NoError(t, err)
+	checkpoint := filepath.Join(dir, "progress.json")
+	saved := &Checkpoint{Kind: "account", Format: "JSON", Input: filename, SHA256: hash,
+		Offset: int64(strings.Index(data, "}") + 1), Records: 1, Batch: 1}
+	assert.NoError(t, saved.save(checkpoint))
+
+	// A transfer migration cannot pick up an account checkpoint.
+	err = tb.MigrateTransfers(filename, MigrateOptions{Checkpoint: checkpoint, Resume: true})
+	assert.ErrorContains(t, err, "belongs to a migration of accounts")
+
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		return len(accounts) == 1 && accounts[0].ID == tbTypes.ToUint128(2)
+	})).Return(nil).Once()
+	err = tb.MigrateAccounts(filename, MigrateOptions{Checkpoint: checkpoint, Resume: true})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+
+	// The checkpoint is removed once the whole file has been migrated.
+	_, err = os.Stat(checkpoint)
+	assert.True(t, os.IsNotExist(err))
+
+	// Resuming is refused once the file has changed.
+	assert.NoError(t, saved.save(checkpoint))
+	assert.NoError(t, os.WriteFile(filename, []byte(data+"\n"), 0o644))
+	err = tb.MigrateAccounts(filename, MigrateOptions{Checkpoint: checkpoint, Resume: true})
+	assert.ErrorContains(t, err, "has changed since checkpoint")
+}
+
+func TestMigrationCheckpointCommit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "transfers.json")
+	assert.NoError(t, os.WriteFile(filename, []byte(`[]`), 0o644))
+
+	input, err := openMigration(filename, "transfer", MigrateOptions{})
+	assert.NoError(t, err)
+	defer input.Close()
+
+	assert.NoError(t, input.commit(batchSize, 1234))
+	assert.NoError(t, input.commit(16380, 2468))
+	saved, err := loadCheckpoint(filename + ".checkpoint")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved.Batch)
+	assert.Equal(t, 16380, saved.Records)
+	assert.Equal(t, int64(2468), saved.Offset)
+	assert.Equal(t, input.checkpoint.SHA256, saved.SHA256)
+}
+
+func TestMigrateDryRunReportsProblems(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+	dir := t.TempDir()
+
+	accountsFile := filepath.Join(dir, "accounts.ndjson")
+	accounts := `{"id": 1001, "ledger": 700, "code": 10}
+{"id": 1002, "ledger": 700, "code": 10}
+{"id": 2001, "ledger": 710, "code": 10}
+`
+	assert.NoError(t, os.WriteFile(accountsFile, []byte(accounts), 0o644))
+
+	err := tb.MigrateAccounts(accountsFile, MigrateOptions{DryRun: true})
+	assert.NoError(t, err)
+
+	badAccounts := filepath.Join(dir, "bad_accounts.ndjson")
+	data := accounts + `{"id": 1002, "ledger": 700, "code": 10}
+{"id": 0, "ledger": 700, "code": 10}
+{"id": 2002, "ledger": 710, "code": 10, "flags": 6}
+`
+	assert.NoError(t, os.WriteFile(badAccounts, []byte(data), 0o644))
+	err = tb.MigrateAccounts(badAccounts, MigrateOptions{DryRun: true})
+	assert.EqualError(t, err, "dry run found 3 problems in 6 accounts")
+
+	fieldAccounts := filepath.Join(dir, "field_accounts.ndjson")
+	data = `{"id": 3001, "ledger": 0, "code": 0}
+{"id": 3002, "ledger": 700, "code": 10, "credits_posted": 500}
+{"id": 3003, "ledger": 700, "code": 10, "debits_pending": 5}
+{"id": 3004, "ledger": 700, "code": 10, "flags": "linked"}
+`
+	assert.NoError(t, os.WriteFile(fieldAccounts, []byte(data), 0o644))
+	err = tb.MigrateAccounts(fieldAccounts, MigrateOptions{DryRun: true})
+	assert.EqualError(t, err, "dry run found 5 problems in 4 accounts")
+	// Opening balances allow posted balances, but never pending ones.
+	err = tb.MigrateAccounts(fieldAccounts, MigrateOptions{DryRun: true, OpeningBalances: true})
+	assert.EqualError(t, err, "dry run found 4 problems in 4 accounts")
+
+	transfersFile := filepath.Join(dir, "transfers.ndjson")
+	transfers := `{"id": 1, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 10, "ledger": 700}
+{"id": 1, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 10, "ledger": 700}
+{"id": 0, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 10, "ledger": 700}
+{"id": 3, "debit_account_id": 1001, "credit_account_id": 1001, "amount": 10, "ledger": 700}
+{"id": 4, "debit_account_id": 1001, "credit_account_id": 2001, "amount": 10, "ledger": 700}
+{"id": 5, "debit_account_id": 1001, "credit_account_id": 3001, "amount": 0, "ledger": 700}
+{"id": 6, "debit_account_id": 1001, "credit_account_id": 4004, "amount": 10, "ledger": 700}
+{"id": 7, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 10, "ledger": 700, "flags": "pending,void_pending_transfer"}
+{"id": "x"}
+{"id": 9, "pending_id": 7, "flags": "void_pending_transfer"}
+{"id": 10, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 10, "ledger": 700, "flags": "linked"}
+`
+	assert.NoError(t, os.WriteFile(transfersFile, []byte(transfers), 0o644))
+
+	// Accounts missing from the accounts file are looked up once each.
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(3001), tbTypes.ToUint128(4004)}).
+		Return([]models.Account{{ID: tbTypes.ToUint128(3001), Ledger: 700}}, nil).Once()
+
+	err = tb.MigrateTransfers(transfersFile, MigrateOptions{DryRun: true, AccountsFile: accountsFile})
+	assert.EqualError(t, err, "dry run found 9 problems in 11 transfers")
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNotCalled(t, "CreateTransfers", mock.Anything)
+}
+
+func TestCheckTransferProblemTypes(t *testing.T) {
+	accounts := &accountLedgers{
+		ledgers: map[tbTypes.Uint128]uint32{tbTypes.ToUint128(1): 700, tbTypes.ToUint128(2): 710},
+		missing: map[tbTypes.Uint128]bool{},
+	}
+	report := newValidationReport("transfer")
+	seen := map[tbTypes.Uint128]int{}
+
+	checkTransfer(report, 0, models.Transfer{ID: tbTypes.ToUint128(5), DebitAccountID: tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2), Ledger: 700}, seen, accounts, nil)
+	checkTransfer(report, 1, models.Transfer{ID: tbTypes.ToUint128(5), DebitAccountID: tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(3), Amount: tbTypes.ToUint128(1), Ledger: 700}, seen, accounts, nil)
+	checkTransfer(report, 2, models.Transfer{ID: tbTypes.ToUint128(6), DebitAccountID: tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2), Amount: tbTypes.ToUint128(1)}, seen, accounts, nil)
+	// A post inherits its ledger from the pending transfer.
+	checkTransfer(report, 3, models.Transfer{ID: tbTypes.ToUint128(7), PendingID: tbTypes.ToUint128(6), DebitAccountID: tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2), Flags: tbTypes.TransferFlags{PostPendingTransfer: true}.ToUint16()}, seen, accounts, nil)
+
+	assert.Equal(t, map[string]int{
+		problemZeroAmount:     1,
+		problemLedgerMismatch: 1,
+		problemDuplicateID:    1,
+		problemUnknownAccount: 1,
+		problemZeroLedger:     1,
+	}, report.counts)
+	assert.Equal(t, "same ID as index 0", report.problems[2].detail)
+}
+
+func TestTimestampOrder(t *testing.T) {
+	timestamps := []uint64{300, 250, 150, 500, 400, 0}
+	linked := []bool{false, false, false, true, false, false}
+
+	// The chain at 3-4 moves as a unit and keeps its own order.
+	order := timestampOrder(len(timestamps),
+		func(i int) uint64 { return timestamps[i] },
+		func(i int) bool { return linked[i] })
+	assert.Equal(t, []int{5, 2, 1, 0, 3, 4}, order)
+}
+
+func TestImportDryRunChecksTimestamps(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient}
+	dir := t.TempDir()
+
+	// Accounts out of timestamp order are sorted within their batch.
+	accountsFile := filepath.Join(dir, "accounts.ndjson")
+	accounts := `{"id": 1002, "ledger": 700, "code": 10, "timestamp": 200}
+{"id": 1001, "ledger": 700, "code": 10, "timestamp": 100}
+`
+	assert.NoError(t, os.WriteFile(accountsFile, []byte(accounts), 0o644))
+	err := tb.MigrateAccounts(accountsFile, MigrateOptions{DryRun: true, Import: true})
+	assert.NoError(t, err)
+
+	transfersFile := filepath.Join(dir, "transfers.ndjson")
+	transfers := `{"id": 1, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 300}
+{"id": 2, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 250}
+{"id": 3, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 150}
+{"id": 4, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 500, "flags": "linked"}
+{"id": 5, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 400}
+{"id": 6, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700}
+{"id": 7, "debit_account_id": 1001, "credit_account_id": 1002, "amount": 1, "ledger": 700, "timestamp": 9223372036854775808}
+`
+	assert.NoError(t, os.WriteFile(transfersFile, []byte(transfers), 0o644))
+
+	// Transfer 3 predates account 1002, the chain 4-5 goes back in time, 6
+	// has no timestamp and 7 is in the future.
+	err = tb.MigrateTransfers(transfersFile, MigrateOptions{DryRun: true, Import: true, AccountsFile: accountsFile})
+	assert.EqualError(t, err, "dry run found 4 problems in 7 transfers")
+
+	// Without --import every timestamp that is set is a problem.
+	err = tb.MigrateTransfers(transfersFile, MigrateOptions{DryRun: true, AccountsFile: accountsFile})
+	assert.EqualError(t, err, "dry run found 6 problems in 7 transfers")
+
+	err = tb.MigrateTransfers(transfersFile, MigrateOptions{Import: true})
+	assert.Equal(t, errImportUnsupported, err)
+
+	// A migration refuses timestamps before anything is sent.
+	err = tb.MigrateTransfers(transfersFile, MigrateOptions{})
+	assert.EqualError(t, err, "transfer at index 0 (ID 1): timestamp 300 must be zero, the cluster assigns it")
+	err = tb.MigrateAccounts(accountsFile, MigrateOptions{})
+	assert.EqualError(t, err, "account at index 0 (ID 1002): timestamp 200 must be zero, the cluster assigns it")
+	mockClient.AssertNotCalled(t, "CreateTransfers", mock.Anything)
+	mockClient.AssertNotCalled(t, "CreateAccounts", mock.Anything)
+}
+
+func TestMigrateAccountsOpeningBalances(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient, ledgers: models.LedgerRegistry{700: {EquityAccount: "9000"}}}
+
+	filename := filepath.Join(t.TempDir(), "accounts.ndjson")
+	data := `{"id": 1, "ledger": 700, "code": 10, "credits_posted": "500"}
+{"id": 2, "ledger": 700, "code": 10, "debits_posted": "200", "credits_posted": "50"}
+{"id": 3, "ledger": 700, "code": 10}
+`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	// Accounts are created with zero balances.
+	mockClient.On("CreateAccounts", mock.MatchedBy(func(accounts []models.Account) bool {
+		for _, account := range accounts {
+			if account.DebitsPosted != (tbTypes.Uint128{}) || account.CreditsPosted != (tbTypes.Uint128{}) {
+				return false
+			}
+		}
+		return len(accounts) == 3
+	})).Return(nil).Once()
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(9000)}).
+		Return([]models.Account{{ID: tbTypes.ToUint128(9000), Ledger: 700}}, nil).Once()
+
+	// Each balance is posted against the equity account, credits first.
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 3 &&
+			transfers[0].ID == openingTransferID(tbTypes.ToUint128(1), "credit") &&
+			transfers[0].DebitAccountID == tbTypes.ToUint128(9000) &&
+			transfers[0].Amount == tbTypes.ToUint128(500) &&
+			transfers[1].CreditAccountID == tbTypes.ToUint128(2) &&
+			transfers[2].DebitAccountID == tbTypes.ToUint128(2) &&
+			transfers[2].Amount == tbTypes.ToUint128(200)
+	})).Return(nil).Once()
+
+	// Account 2 does not end up with the balance from the file.
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(1), tbTypes.ToUint128(2)}).Return([]models.Account{
+		{ID: tbTypes.ToUint128(1), Ledger: 700, CreditsPosted: tbTypes.ToUint128(500)},
+		{ID: tbTypes.ToUint128(2), Ledger: 700, DebitsPosted: tbTypes.ToUint128(100), CreditsPosted: tbTypes.ToUint128(50)},
+	}, nil).Once()
+
+	err := tb.MigrateAccounts(filename, MigrateOptions{OpeningBalances: true})
+	assert.EqualError(t, err, "error opening balances in batch 0-2: 1 accounts failed")
+	mockClient.AssertExpectations(t)
+
+	// Ledgers without an equity account are refused before anything is
+	// created.
+	tb.ledgers = nil
+	err = tb.MigrateAccounts(filename, MigrateOptions{OpeningBalances: true})
+	assert.ErrorContains(t, err, "no equity account for ledger 700")
+
+	pending := filepath.Join(t.TempDir(), "pending.json")
+	assert.NoError(t, os.WriteFile(pending, []byte(`[{"id": 4, "ledger": 700, "code": 10, "debits_pending": "1"}]`), 0o644))
+	err = tb.MigrateAccounts(pending, MigrateOptions{OpeningBalances: true, EquityAccount: tbTypes.ToUint128(9000)})
+	assert.EqualError(t, err, "account at index 0: opening balances cannot include pending amounts")
+}
+
+func TestMigrateAccountsOpeningBalancesEquityInFile(t *testing.T) {
+	mockClient := new(MockClient)
+	tb := &TigerBeagle{client: mockClient, ledgers: models.LedgerRegistry{700: {EquityAccount: "9000"}}}
+
+	filename := filepath.Join(t.TempDir(), "accounts.ndjson")
+	data := `{"id": 9000, "ledger": 700, "code": 1}
+{"id": 1, "ledger": 700, "code": 10, "credits_posted": "500"}
+`
+	assert.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+
+	// The equity account is created with the batch and only looked up after.
+	created := false
+	mockClient.On("CreateAccounts", mock.Anything).Return(nil).Run(func(mock.Arguments) { created = true }).Once()
+	mockClient.On("LookupAccounts", mock.MatchedBy(func(ids []tbTypes.Uint128) bool {
+		return created && len(ids) == 1 && ids[0] == tbTypes.ToUint128(9000)
+	})).Return([]models.Account{{ID: tbTypes.ToUint128(9000), Ledger: 700}}, nil).Once()
+	mockClient.On("CreateTransfers", mock.MatchedBy(func(transfers []models.Transfer) bool {
+		return len(transfers) == 1 && transfers[0].DebitAccountID == tbTypes.ToUint128(9000)
+	})).Return(nil).Once()
+	mockClient.On("LookupAccounts", []tbTypes.Uint128{tbTypes.ToUint128(1)}).
+		Return([]models.Account{{ID: tbTypes.ToUint128(1), Ledger: 700, CreditsPosted: tbTypes.ToUint128(500)}}, nil).Once()
+
+	err := tb.MigrateAccounts(filename, MigrateOptions{OpeningBalances: true})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+
+	// An equity account in the file must still be on the right ledger.
+	tb.ledgers = models.LedgerRegistry{710: {EquityAccount: "9000"}}
+	wrong := filepath.Join(t.TempDir(), "wrong.ndjson")
+	assert.NoError(t, os.WriteFile(wrong, []byte(`{"id": 9000, "ledger": 700, "code": 1}
+{"id": 2, "ledger": 710, "code": 10, "credits_posted": "500"}
+`), 0o644))
+	err = tb.MigrateAccounts(wrong, MigrateOptions{OpeningBalances: true})
+	assert.EqualError(t, err, "equity account 9000 is on ledger 700, not ledger 710")
 }
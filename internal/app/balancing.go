@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// maxAmount is the largest transfer amount, used as the upper bound of a
+// sweep so the balancing flag alone decides how much moves.
+var maxAmount = tbTypes.BytesToUint128([16]byte{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+})
+
+// Sweep moves the full available balance of one account to another in a
+// single balancing transfer. The cluster clamps the amount to what the
+// source account holds when the transfer is applied, so there is no window
+// between reading the balance and moving it.
+func (t *TigerBeagle) Sweep(fromAccountID, toAccountID tbTypes.Uint128, ledger uint32, code uint16) error {
+	transfer := models.Transfer{
+		ID:              t.nextID(),
+		DebitAccountID:  fromAccountID,
+		CreditAccountID: toAccountID,
+		Amount:          maxAmount,
+		Ledger:          ledger,
+		Code:            code,
+		Flags:           tbTypes.TransferFlags{BalancingDebit: true}.ToUint16(),
+	}
+
+	if err := t.client.CreateTransfers([]models.Transfer{transfer}); err != nil {
+		return fmt.Errorf("error sweeping account %s: %w", models.FormatUint128(fromAccountID), err)
+	}
+
+	moved, err := t.movedAmount(transfer.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Transfer %s swept %s from account %s to account %s (Ledger: %d, Code: %d)\n",
+		models.FormatUint128(transfer.ID), t.ledgers.FormatUint128Amount(moved, ledger),
+		models.FormatUint128(fromAccountID), models.FormatUint128(toAccountID), ledger, code)
+	return nil
+}
+
+// isBalancing reports whether flags make the cluster choose the transfer
+// amount.
+func isBalancing(flags uint16) bool {
+	balancing := tbTypes.TransferFlags{BalancingDebit: true, BalancingCredit: true}.ToUint16()
+	return flags&balancing != 0
+}
+
+// movedAmount looks up a created transfer to find the amount the cluster
+// actually moved, which for a balancing transfer may be less than requested.
+func (t *TigerBeagle) movedAmount(id tbTypes.Uint128) (tbTypes.Uint128, error) {
+	transfers, err := t.client.LookupTransfers([]tbTypes.Uint128{id})
+	if err != nil {
+		return tbTypes.Uint128{}, fmt.Errorf("error fetching transfer %s: %w", models.FormatUint128(id), err)
+	}
+	if len(transfers) == 0 {
+		return tbTypes.Uint128{}, fmt.Errorf("transfer %s not found after creation", models.FormatUint128(id))
+	}
+	return transfers[0].Amount, nil
+}
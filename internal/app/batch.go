@@ -0,0 +1,114 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// chainSpan locates one chain from the input within a submitted batch.
+type chainSpan struct {
+	record int
+	start  int
+	size   int
+}
+
+// BatchTransfer creates transfers from a file of chains, where each chain
+// succeeds or fails as a unit. Chains are packed into batches without ever
+// being split across requests, and a failed chain is reported once, with
+// the transfer that caused it. Transfers without an ID are assigned one from
+// the ID generator.
+func (t *TigerBeagle) BatchTransfer(filename string) error {
+	file, size, err := openMigrationFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stream := models.NewRecordStream(file)
+	batch := make([]models.Transfer, 0, batchSize)
+	var spans []chainSpan
+	committed, transfers, failedChains := 0, 0, 0
+
+	submit := func() error {
+		first, last := spans[0].record, spans[len(spans)-1].record
+
+		var failed tigerbeetle.TransferErrors
+		err := t.client.CreateTransfers(batch)
+		if err != nil && !errors.As(err, &failed) {
+			return fmt.Errorf("error creating transfers for chains %d-%d: %w", first, last, err)
+		}
+
+		// Failed results are in batch order, as are the spans.
+		for _, span := range spans {
+			var results []tigerbeetle.TransferResult
+			for len(failed) > 0 && failed[0].Index < span.start+span.size {
+				results = append(results, failed[0])
+				failed = failed[1:]
+			}
+			if len(results) == 0 {
+				committed++
+				transfers += span.size
+				continue
+			}
+
+			failedChains++
+			cause := results[0]
+			for _, r := range results {
+				if r.Result != tbTypes.TransferLinkedEventFailed {
+					cause = r
+					break
+				}
+			}
+			fmt.Printf("Chain at index %d (%d transfers) failed: transfer %d of the chain (ID %s): %s\n",
+				span.record, span.size, cause.Index-span.start, models.FormatUint128(cause.ID), cause.Result)
+		}
+
+		fmt.Printf("Processed chains %d-%d (%s)\n", first, last, progress(stream.Offset(), size))
+		batch = batch[:0]
+		spans = spans[:0]
+		return nil
+	}
+
+	for {
+		chain, err := stream.NextTransferChain(t.ledgers)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error parsing JSON: %w", err)
+		}
+		if len(chain) > batchSize {
+			return fmt.Errorf("chain at index %d has %d transfers, more than the batch size of %d", stream.Index()-1, len(chain), batchSize)
+		}
+
+		if len(batch)+len(chain) > batchSize {
+			if err := submit(); err != nil {
+				return err
+			}
+		}
+
+		for i := range chain {
+			if chain[i].ID == (tbTypes.Uint128{}) {
+				chain[i].ID = t.nextID()
+			}
+		}
+		spans = append(spans, chainSpan{record: stream.Index() - 1, start: len(batch), size: len(chain)})
+		batch = append(batch, chain...)
+	}
+	if len(batch) > 0 {
+		if err := submit(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Committed %d chains (%d transfers), %d chains failed\n", committed, transfers, failedChains)
+	if failedChains > 0 {
+		return fmt.Errorf("%d of %d chains failed", failedChains, committed+failedChains)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// batchErrors collects the errors of every batch that failed before a bulk
+// submission was cancelled.
+type batchErrors []error
+
+func (e batchErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d batches failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first batch error.
+func (e batchErrors) Unwrap() error {
+	return e[0]
+}
+
+// bulkBatch is a batch of transfers and the index of its first transfer.
+type bulkBatch struct {
+	start     int
+	transfers []models.Transfer
+}
+
+// BulkTransfer creates iterations identical transfers in batches. Batches are
+// generated as they are needed and submitted by up to SetConcurrency workers
+// sharing the client, so several can be in flight at once. The first failed
+// batch stops further batches from being submitted; the errors of every
+// batch that failed are returned together.
+func (t *TigerBeagle) BulkTransfer(iterations int, debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error {
+	if err := models.ValidateTransferFlags(flags); err != nil {
+		return fmt.Errorf("invalid transfer flags: %w", err)
+	}
+
+	workers := t.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	batches := make(chan bulkBatch)
+	done := make(chan struct{})
+	var cancel sync.Once
+
+	var mu sync.Mutex
+	var failed batchErrors
+
+	// Generate each batch only when a worker is ready for it, and stop as
+	// soon as a batch has failed.
+	go func() {
+		defer close(batches)
+		for start := 0; start < iterations; start += batchSize {
+			size := iterations - start
+			if size > batchSize {
+				size = batchSize
+			}
+			transfers := make([]models.Transfer, size)
+			for i := range transfers {
+				transfers[i] = models.Transfer{
+					ID:              t.nextID(),
+					DebitAccountID:  debitAccountID,
+					CreditAccountID: creditAccountID,
+					Amount:          tbTypes.ToUint128(amount),
+					Ledger:          ledger,
+					Code:            code,
+					Flags:           flags,
+				}
+			}
+
+			select {
+			case batches <- bulkBatch{start: start, transfers: transfers}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				select {
+				case <-done:
+					continue
+				default:
+				}
+
+				end := batch.start + len(batch.transfers) - 1
+				if err := t.client.CreateTransfers(batch.transfers); err != nil {
+					err = fmt.Errorf("error creating transfers in batch %d-%d: %w", batch.start, end, reportTransferErrors(err, batch.start))
+					mu.Lock()
+					failed = append(failed, err)
+					mu.Unlock()
+					cancel.Do(func() { close(done) })
+					continue
+				}
+				fmt.Printf("Processed transfers %d-%d\n", batch.start, end)
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch len(failed) {
+	case 0:
+		fmt.Printf("All %d transfers completed successfully\n", iterations)
+		return nil
+	case 1:
+		return failed[0]
+	default:
+		return failed
+	}
+}
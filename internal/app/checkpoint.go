@@ -0,0 +1,209 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+)
+
+// Checkpoint records the last migration batch the cluster confirmed, so that
+// an interrupted migration can be resumed after it.
+type Checkpoint struct {
+	Kind   string `json:"kind"`
+	Format string `json:"format"`
+	Input  string `json:"input"`
+	SHA256 string `json:"sha256"`
+	// Offset is the byte offset in the input just past the last record of
+	// the batch, and Records the number of input records up to that point.
+	Offset  int64 `json:"offset"`
+	Records int   `json:"records"`
+	Batch   int   `json:"batch"`
+}
+
+// checkpointPath returns where the checkpoint for a migration of filename is
+// kept.
+func checkpointPath(filename string, opts MigrateOptions) string {
+	if opts.Checkpoint != "" {
+		return opts.Checkpoint
+	}
+	return filename + ".checkpoint"
+}
+
+// loadCheckpoint reads the checkpoint at path. It returns nil if there is
+// none.
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("error reading checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// save writes the checkpoint to path, replacing any previous one atomically
+// so that an interruption never leaves a partial checkpoint behind.
+func (c *Checkpoint) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// hashFile returns the SHA-256 of file's contents and rewinds it.
+func hashFile(file *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// migrationInput is an open migration file, positioned at the first record
+// still to be migrated, and the checkpoint that tracks its progress.
+type migrationInput struct {
+	file       *os.File
+	size       int64
+	stream     recordSource
+	format     string
+	path       string
+	checkpoint Checkpoint
+}
+
+// openMigration opens filename for a migration of kind records. With
+// opts.Resume it continues after the batch recorded in the checkpoint, and
+// refuses to if the file has changed since.
+func openMigration(filename, kind string, opts MigrateOptions) (*migrationInput, error) {
+	file, size, err := openMigrationFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := &migrationInput{file: file, size: size, path: checkpointPath(filename, opts)}
+
+	hash, err := hashFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	m.format = inputFormat(opts)
+	m.checkpoint = Checkpoint{Kind: kind, Format: m.format, Input: filename, SHA256: hash}
+
+	var saved *Checkpoint
+	if opts.Resume {
+		if saved, err = loadCheckpoint(m.path); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if saved == nil {
+			fmt.Printf("No checkpoint at %s, starting from the beginning\n", m.path)
+		}
+	}
+	if saved != nil {
+		if err := m.resume(saved, opts); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return m, nil
+	}
+
+	m.stream, err = openRecordSource(file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// resume positions the input after the records covered by saved.
+func (m *migrationInput) resume(saved *Checkpoint, opts MigrateOptions) error {
+	switch {
+	case saved.Kind != m.checkpoint.Kind:
+		return fmt.Errorf("cannot resume: checkpoint %s belongs to a migration of %ss", m.path, saved.Kind)
+	case saved.Format != m.format:
+		return fmt.Errorf("cannot resume: checkpoint %s was written for %s input", m.path, saved.Format)
+	case saved.SHA256 != m.checkpoint.SHA256:
+		return fmt.Errorf("cannot resume: %s has changed since checkpoint %s was written", m.checkpoint.Input, m.path)
+	}
+
+	if opts.Mapping == nil {
+		stream, err := models.ResumeRecordStream(m.file, saved.Offset, saved.Records)
+		if err != nil {
+			return fmt.Errorf("error resuming from checkpoint: %w", err)
+		}
+		m.stream = stream
+	} else {
+		// CSV rows are skipped by count rather than by seeking, since the
+		// reader buffers ahead of the row it returns.
+		stream, err := models.NewCSVStream(m.file, *opts.Mapping)
+		if err != nil {
+			return err
+		}
+		if err := stream.Skip(saved.Records); err != nil {
+			return fmt.Errorf("error resuming from checkpoint: %w", err)
+		}
+		m.stream = stream
+	}
+
+	m.checkpoint.Offset = saved.Offset
+	m.checkpoint.Records = saved.Records
+	m.checkpoint.Batch = saved.Batch
+	fmt.Printf("Resuming after batch %d (%d records, %s)\n", saved.Batch, saved.Records, progress(saved.Offset, m.size))
+	return nil
+}
+
+// commit records that a batch was confirmed by the cluster, covering the
+// first records input records up to offset.
+func (m *migrationInput) commit(records int, offset int64) error {
+	m.checkpoint.Batch++
+	m.checkpoint.Records = records
+	m.checkpoint.Offset = offset
+	return m.checkpoint.save(m.path)
+}
+
+// finish removes the checkpoint once the whole input has been migrated.
+func (m *migrationInput) finish() error {
+	if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (m *migrationInput) Close() error {
+	return m.file.Close()
+}
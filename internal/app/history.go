@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// HistoryFilter selects the transfers or balance snapshots of an account.
+// Timestamps are cluster timestamps in nanoseconds and are inclusive; zero
+// leaves that end open. When neither Debits nor Credits is set, both are
+// included.
+type HistoryFilter struct {
+	AccountID tbTypes.Uint128
+	Since     uint64
+	Until     uint64
+	Debits    bool
+	Credits   bool
+	Reverse   bool
+	// Limit caps the total number of transfers or balance snapshots
+	// returned; zero means all.
+	Limit int
+}
+
+// AccountHistory pages through the transfers of an account in timestamp
+// order, calling visit for each one. Pages are fetched as needed, so the
+// full history is never held in memory.
+func (t *TigerBeagle) AccountHistory(filter HistoryFilter, visit func(*models.Transfer) error) error {
+	return paginate(filter, func(query tbTypes.AccountFilter) (int, uint64, error) {
+		page, err := t.client.GetAccountTransfers(query)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error fetching account history: %w", err)
+		}
+
+		for i := range page {
+			if err := visit(&page[i]); err != nil {
+				return 0, 0, err
+			}
+		}
+		if len(page) == 0 {
+			return 0, 0, nil
+		}
+		return len(page), page[len(page)-1].Timestamp, nil
+	})
+}
+
+// BalanceHistory pages through the balance snapshots of an account created
+// with the history flag, calling visit with the account's balances after
+// each matching transfer.
+func (t *TigerBeagle) BalanceHistory(filter HistoryFilter, visit func(*models.AccountBalances) error) error {
+	accounts, err := t.client.LookupAccounts([]tbTypes.Uint128{filter.AccountID})
+	if err != nil {
+		return fmt.Errorf("error fetching account: %w", err)
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("account %s not found", models.FormatUint128(filter.AccountID))
+	}
+	account := accounts[0]
+	if account.Flags&(tbTypes.AccountFlags{History: true}).ToUint16() == 0 {
+		return fmt.Errorf("account %s was not created with the history flag", models.FormatUint128(filter.AccountID))
+	}
+
+	return paginate(filter, func(query tbTypes.AccountFilter) (int, uint64, error) {
+		page, err := t.client.GetAccountBalances(query)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error fetching balance history: %w", err)
+		}
+
+		for _, snapshot := range page {
+			ab := &models.AccountBalances{Account: account, Side: models.NormalSideOf(account.Flags)}
+			ab.FromTigerBeetleAccountBalance(snapshot)
+			if err := visit(ab); err != nil {
+				return 0, 0, err
+			}
+		}
+		if len(page) == 0 {
+			return 0, 0, nil
+		}
+		return len(page), page[len(page)-1].Timestamp, nil
+	})
+}
+
+// paginate runs fetch with successive account filters until the results
+// matching filter are exhausted. fetch returns the number of results in the
+// page and the timestamp of the last one.
+func paginate(filter HistoryFilter, fetch func(query tbTypes.AccountFilter) (int, uint64, error)) error {
+	debits, credits := filter.Debits, filter.Credits
+	if !debits && !credits {
+		debits, credits = true, true
+	}
+
+	query := tbTypes.AccountFilter{
+		AccountID:    filter.AccountID,
+		TimestampMin: filter.Since,
+		TimestampMax: filter.Until,
+		Flags: tbTypes.AccountFilterFlags{
+			Debits:   debits,
+			Credits:  credits,
+			Reversed: filter.Reverse,
+		}.ToUint32(),
+	}
+
+	seen := 0
+	for {
+		limit := batchSize
+		if filter.Limit > 0 && filter.Limit-seen < limit {
+			limit = filter.Limit - seen
+		}
+		query.Limit = uint32(limit)
+
+		n, last, err := fetch(query)
+		if err != nil {
+			return err
+		}
+		seen += n
+
+		if n < limit || (filter.Limit > 0 && seen >= filter.Limit) {
+			return nil
+		}
+
+		// Continue after the last result of this page.
+		if filter.Reverse {
+			query.TimestampMax = last - 1
+		} else {
+			query.TimestampMin = last + 1
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sync"
+
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// IDGenerator produces IDs for the transfers TigerBeagle creates.
+// Implementations must be safe for concurrent use.
+type IDGenerator interface {
+	NextID() tbTypes.Uint128
+}
+
+// ULIDGenerator produces time-ordered, ULID-style 128-bit IDs, as recommended
+// by TigerBeetle. It is the default strategy.
+type ULIDGenerator struct{}
+
+func (ULIDGenerator) NextID() tbTypes.Uint128 {
+	return tbTypes.ID()
+}
+
+// SequentialIDGenerator hands out caller-supplied IDs, starting at a given
+// value and counting up by one for every further ID.
+type SequentialIDGenerator struct {
+	mu   sync.Mutex
+	next big.Int
+}
+
+// NewSequentialIDGenerator returns a generator whose first ID is start.
+func NewSequentialIDGenerator(start tbTypes.Uint128) (*SequentialIDGenerator, error) {
+	if start == (tbTypes.Uint128{}) {
+		return nil, fmt.Errorf("id must not be zero")
+	}
+	return &SequentialIDGenerator{next: start.BigInt()}, nil
+}
+
+func (g *SequentialIDGenerator) NextID() tbTypes.Uint128 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := tbTypes.BigIntToUint128(g.next)
+	g.next.Add(&g.next, big.NewInt(1))
+	return id
+}
+
+// ReferenceIDGenerator derives IDs deterministically from an external
+// reference, so retrying the same command produces the same IDs and the
+// cluster reports the transfers as existing instead of duplicating them. The
+// first ID is the SHA-256 of the reference truncated to 128 bits; later IDs
+// hash the reference together with their sequence number.
+type ReferenceIDGenerator struct {
+	mu        sync.Mutex
+	reference string
+	count     uint64
+}
+
+func NewReferenceIDGenerator(reference string) (*ReferenceIDGenerator, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("reference must not be empty")
+	}
+	return &ReferenceIDGenerator{reference: reference}, nil
+}
+
+func (g *ReferenceIDGenerator) NextID() tbTypes.Uint128 {
+	g.mu.Lock()
+	input := g.reference
+	if g.count > 0 {
+		input = fmt.Sprintf("%s#%d", g.reference, g.count)
+	}
+	g.count++
+	g.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(input))
+	var id [16]byte
+	copy(id[:], sum[:16])
+	return tbTypes.BytesToUint128(id)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// errImportUnsupported is returned by an import that would submit records.
+// Keeping the original timestamps needs the imported account and transfer
+// flags, which the tigerbeetle-go 0.15.3 client does not have; a cluster of
+// that version rejects every non-zero timestamp with TimestampMustBeZero.
+var errImportUnsupported = errors.New("historical import is not supported by this build's TigerBeetle client (0.15.3), which has no imported flag; check the file with a dry run instead")
+
+// timestampSetError is returned for a record that carries its own timestamp
+// outside an import. The cluster would reject it with TimestampMustBeZero.
+func timestampSetError(kind string, index int, id tbTypes.Uint128, timestamp uint64) error {
+	return fmt.Errorf("%s at index %d (ID %s): timestamp %d must be zero, the cluster assigns it", kind, index, models.FormatUint128(id), timestamp)
+}
+
+// timestampOrder returns the order in which to import n records, as indexes
+// into the batch, sorted by timestamp where that is safe: linked chains
+// move as a unit and keep their own order, and records with equal
+// timestamps keep their input order.
+func timestampOrder(n int, timestamp func(i int) uint64, linked func(i int) bool) []int {
+	type unit struct{ start, end int }
+	var units []unit
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && linked(end-1) {
+			end++
+		}
+		units = append(units, unit{start, end})
+		start = end
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		return timestamp(units[i].start) < timestamp(units[j].start)
+	})
+
+	order := make([]int, 0, n)
+	for _, u := range units {
+		for i := u.start; i < u.end; i++ {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// importClock checks that imported records carry timestamps in the past
+// that strictly increase through the file.
+type importClock struct {
+	now       uint64
+	last      uint64
+	lastIndex int
+}
+
+func newImportClock() *importClock {
+	return &importClock{now: uint64(time.Now().UnixNano()), lastIndex: -1}
+}
+
+// check adds any problem with the timestamp of the record at index, in the
+// order the records would be imported.
+func (c *importClock) check(report *validationReport, index int, id tbTypes.Uint128, timestamp uint64) {
+	switch {
+	case timestamp == 0:
+		report.add(problemMissingTimestamp, index, id, "timestamp is zero")
+		return
+	case timestamp >= c.now:
+		report.add(problemFutureTimestamp, index, id, "timestamp %d is in the future", timestamp)
+	}
+	if c.lastIndex >= 0 && timestamp <= c.last {
+		report.add(problemTimestampOrder, index, id, "timestamp %d is not after %d at index %d", timestamp, c.last, c.lastIndex)
+		return
+	}
+	c.last, c.lastIndex = timestamp, index
+}
@@ -0,0 +1,545 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// MigrateOptions controls how MigrateAccounts and MigrateTransfers apply a
+// file to the cluster.
+type MigrateOptions struct {
+	// Idempotent makes a migration safe to re-run: records that already exist
+	// unchanged count as applied, records that exist with different fields
+	// are reported as conflicts, and processing continues past failed rows.
+	Idempotent bool
+	// Mapping, when set, reads the input as CSV with these column mappings
+	// instead of JSON. In idempotent mode, rows with invalid values are
+	// reported with their line number and skipped.
+	Mapping *models.CSVMapping
+	// Checkpoint is where progress is recorded after every committed batch.
+	// It defaults to the input file name with ".checkpoint" appended.
+	Checkpoint string
+	// Resume continues a migration after the last batch recorded in the
+	// checkpoint, provided the input has not changed since.
+	Resume bool
+	// DryRun checks the input and reports problems without creating
+	// anything in the cluster.
+	DryRun bool
+	// AccountsFile is a JSON accounts file whose accounts a transfer dry run
+	// treats as existing, as if it had been migrated first.
+	AccountsFile string
+	// Import keeps the timestamps from the file, which must be set and
+	// strictly increasing. Only dry runs are supported; see
+	// errImportUnsupported.
+	Import bool
+	// OpeningBalances creates accounts with zero balances and then posts the
+	// posted balances from the file as transfers against an equity account,
+	// checking the result.
+	OpeningBalances bool
+	// EquityAccount is the equity account for opening balances on every
+	// ledger. When zero, each ledger's equity_account from the registry is
+	// used.
+	EquityAccount tbTypes.Uint128
+}
+
+// recordSource is a stream of migration records, read from JSON or CSV.
+type recordSource interface {
+	NextAccount(account *models.Account, ledgers models.LedgerRegistry) error
+	NextTransfer(transfer *models.Transfer, ledgers models.LedgerRegistry) error
+	Index() int
+	Offset() int64
+}
+
+// inputFormat names the format of a migration input for messages.
+func inputFormat(opts MigrateOptions) string {
+	if opts.Mapping != nil {
+		return "CSV"
+	}
+	return "JSON"
+}
+
+// openRecordSource returns the record stream for a migration input.
+func openRecordSource(r io.Reader, opts MigrateOptions) (recordSource, error) {
+	if opts.Mapping == nil {
+		return models.NewRecordStream(r), nil
+	}
+	return models.NewCSVStream(r, *opts.Mapping)
+}
+
+// eventResult is a failed account or transfer event, with its index in the
+// migration input.
+type eventResult struct {
+	index    int
+	id       tbTypes.Uint128
+	result   string
+	exists   bool
+	conflict bool
+	// linkedFailed marks an event that failed only because another event in
+	// its linked chain did.
+	linkedFailed bool
+	// chain is the input index of the first event of the event's linked
+	// chain, or its own index when it is not linked.
+	chain int
+}
+
+func accountEventResults(failed tigerbeetle.AccountErrors, chains map[int]int) []eventResult {
+	results := make([]eventResult, len(failed))
+	for i, r := range failed {
+		results[i] = eventResult{r.Index, r.ID, r.Result.String(), r.Exists(), r.Conflict(), r.LinkedEventFailed(), chainOf(chains, r.Index)}
+	}
+	return settleChains(results)
+}
+
+func transferEventResults(failed tigerbeetle.TransferErrors, chains map[int]int) []eventResult {
+	results := make([]eventResult, len(failed))
+	for i, r := range failed {
+		results[i] = eventResult{r.Index, r.ID, r.Result.String(), r.Exists(), r.Conflict(), r.LinkedEventFailed(), chainOf(chains, r.Index)}
+	}
+	return settleChains(results)
+}
+
+// chainStarts maps the input index of every event of a linked chain in a
+// batch to the input index of the chain's first event. linked reports
+// whether the event at a batch position links to the next one.
+func chainStarts(positions []int, linked func(i int) bool) map[int]int {
+	chains := make(map[int]int)
+	start := -1
+	for i, index := range positions {
+		if start < 0 && linked(i) {
+			start = index
+		}
+		if start >= 0 {
+			chains[index] = start
+			if !linked(i) {
+				start = -1
+			}
+		}
+	}
+	return chains
+}
+
+func chainOf(chains map[int]int, index int) int {
+	if start, ok := chains[index]; ok {
+		return start
+	}
+	return index
+}
+
+// settleChains marks every event of an already applied linked chain as
+// existing. Replaying such a chain fails one event with exists and the rest
+// with linked_event_failed, so a chain counts as applied when exists is its
+// only other failure.
+func settleChains(results []eventResult) []eventResult {
+	members := make(map[int][]int)
+	for i, r := range results {
+		members[r.chain] = append(members[r.chain], i)
+	}
+	for _, chain := range members {
+		exists, applied := false, true
+		for _, i := range chain {
+			switch r := results[i]; {
+			case r.exists:
+				exists = true
+			case !r.linkedFailed:
+				applied = false
+			}
+		}
+		if exists && applied {
+			for _, i := range chain {
+				results[i].exists = true
+			}
+		}
+	}
+	return results
+}
+
+// migrationSummary tallies the outcome of every record in a migration.
+type migrationSummary struct {
+	kind      string
+	created   int
+	existing  int
+	conflicts []eventResult
+	failures  []eventResult
+	badRows   []error
+}
+
+// skip records an input row that could not be parsed. It reports whether
+// err is such a row; other errors must stop the migration.
+func (s *migrationSummary) skip(err error) bool {
+	var rowErr *models.CSVRecordError
+	if !errors.As(err, &rowErr) {
+		return false
+	}
+	s.badRows = append(s.badRows, rowErr)
+	return true
+}
+
+// add records the outcome of a batch of size records, of which failed were
+// rejected by the cluster.
+func (s *migrationSummary) add(size int, failed []eventResult) {
+	s.created += size - len(failed)
+	for _, r := range failed {
+		switch {
+		case r.exists:
+			s.existing++
+		case r.conflict:
+			s.conflicts = append(s.conflicts, r)
+		default:
+			s.failures = append(s.failures, r)
+		}
+	}
+}
+
+func (s *migrationSummary) print() {
+	fmt.Printf("Created %d %ss, %d already existed, %d conflicts, %d failed\n",
+		s.created, s.kind, s.existing, len(s.conflicts), len(s.failures))
+	if len(s.conflicts) > 0 {
+		fmt.Println("Conflicts:")
+		for _, r := range s.conflicts {
+			fmt.Printf("  %s at index %d (ID %s): %s\n", s.kind, r.index, models.FormatUint128(r.id), r.result)
+		}
+	}
+	if len(s.failures) > 0 {
+		fmt.Println("Failures:")
+		for _, r := range s.failures {
+			fmt.Printf("  %s at index %d (ID %s): %s\n", s.kind, r.index, models.FormatUint128(r.id), r.result)
+		}
+	}
+	if len(s.badRows) > 0 {
+		fmt.Printf("Skipped %d invalid rows:\n", len(s.badRows))
+		for _, err := range s.badRows {
+			fmt.Printf("  %v\n", err)
+		}
+	}
+}
+
+func (s *migrationSummary) err() error {
+	if len(s.conflicts) == 0 && len(s.failures) == 0 && len(s.badRows) == 0 {
+		return nil
+	}
+	if len(s.badRows) > 0 {
+		return fmt.Errorf("%s migration finished with %d conflicts, %d failures and %d invalid rows", s.kind, len(s.conflicts), len(s.failures), len(s.badRows))
+	}
+	return fmt.Errorf("%s migration finished with %d conflicts and %d failures", s.kind, len(s.conflicts), len(s.failures))
+}
+
+// MigrateAccounts streams accounts from a JSON array or newline-delimited
+// JSON file and creates them in batches, so memory use does not grow with
+// the size of the file.
+func (t *TigerBeagle) MigrateAccounts(filename string, opts MigrateOptions) error {
+	if opts.DryRun {
+		return t.validateAccounts(filename, opts)
+	}
+	if opts.Import {
+		return errImportUnsupported
+	}
+
+	input, err := openMigration(filename, "account", opts)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := input.stream
+	summary := &migrationSummary{kind: "account"}
+	batch := make([]models.Account, 0, batchSize)
+	// positions holds the input index of each account in the batch, which
+	// differ from their batch positions once invalid rows are skipped.
+	positions := make([]int, 0, batchSize)
+	// ends holds the input offset just past each account in the batch, for
+	// checkpointing when the tail of the batch is held back.
+	ends := make([]int64, 0, batchSize)
+	linked := tbTypes.AccountFlags{Linked: true}.ToUint16()
+
+	var opening *openingBalances
+	// balances holds the accounts of the batch with their balances from the
+	// file, which are zero in batch itself.
+	var balances []models.Account
+	if opts.OpeningBalances {
+		opening = newOpeningBalances(t, opts)
+		balances = make([]models.Account, 0, batchSize)
+	}
+
+	// submit creates the first n accounts of the batch and keeps the rest
+	// for the next one.
+	submit := func(n int) error {
+		start, end := positions[0], positions[n-1]
+
+		if opening != nil {
+			if err := opening.prepare(balances[:n]); err != nil {
+				return err
+			}
+		}
+
+		var failed tigerbeetle.AccountErrors
+		err := t.client.CreateAccounts(batch[:n])
+		if errors.As(err, &failed) {
+			failed = failed.Remap(positions[:n])
+			err = failed
+		}
+		if err != nil && (!opts.Idempotent || failed == nil) {
+			return fmt.Errorf("error creating accounts in batch %d-%d: %w", start, end, reportAccountErrors(err, 0))
+		}
+		chains := chainStarts(positions[:n], func(i int) bool { return batch[i].Flags&linked != 0 })
+		events := accountEventResults(failed, chains)
+		summary.add(n, events)
+
+		if opening != nil {
+			skip := make(map[int]bool)
+			for _, r := range events {
+				if !r.exists {
+					skip[r.index] = true
+				}
+			}
+			results, err := opening.post(balances[:n], positions[:n], skip)
+			if err != nil {
+				return err
+			}
+			if len(results) > 0 && !opts.Idempotent {
+				for _, r := range results {
+					fmt.Printf("Account at index %d (ID %s) failed: %s\n", r.index, models.FormatUint128(r.id), r.result)
+				}
+				return fmt.Errorf("error opening balances in batch %d-%d: %d accounts failed", start, end, len(results))
+			}
+			summary.failures = append(summary.failures, results...)
+			balances = balances[:copy(balances, balances[n:])]
+		}
+
+		if err := input.commit(end+1, ends[n-1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Processed accounts %d-%d (%s)\n", start, end, progress(ends[n-1], input.size))
+		batch = batch[:copy(batch, batch[n:])]
+		positions = positions[:copy(positions, positions[n:])]
+		ends = ends[:copy(ends, ends[n:])]
+		return nil
+	}
+
+	for {
+		var account models.Account
+		err := stream.NextAccount(&account, t.ledgers)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if opts.Idempotent && summary.skip(err) {
+				continue
+			}
+			return fmt.Errorf("error parsing %s: %w", input.format, err)
+		}
+		if account.Timestamp != 0 {
+			return timestampSetError("account", stream.Index()-1, account.ID, account.Timestamp)
+		}
+
+		if opening != nil {
+			balance, err := opening.take(&account, stream.Index()-1)
+			if err != nil {
+				return err
+			}
+			balances = append(balances, balance)
+		}
+
+		batch = append(batch, account)
+		positions = append(positions, stream.Index()-1)
+		ends = append(ends, stream.Offset())
+		if len(batch) == batchSize {
+			// Never split a linked chain across requests: hold back any
+			// chain still open at the end of the batch.
+			n := chainBoundary(len(batch), func(i int) bool { return batch[i].Flags&linked != 0 })
+			if n == 0 {
+				return fmt.Errorf("linked chain starting at index %d is longer than the batch size of %d", positions[0], batchSize)
+			}
+			if err := submit(n); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := submit(len(batch)); err != nil {
+			return err
+		}
+	}
+	if err := input.finish(); err != nil {
+		return err
+	}
+
+	if !opts.Idempotent {
+		fmt.Printf("Successfully migrated all %d accounts\n", stream.Index())
+		return nil
+	}
+	summary.print()
+	return summary.err()
+}
+
+// MigrateTransfers streams transfers from a JSON array or newline-delimited
+// JSON file and creates them in batches, so memory use does not grow with
+// the size of the file.
+func (t *TigerBeagle) MigrateTransfers(filename string, opts MigrateOptions) error {
+	if opts.DryRun {
+		return t.validateTransfers(filename, opts)
+	}
+	if opts.Import {
+		return errImportUnsupported
+	}
+
+	input, err := openMigration(filename, "transfer", opts)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := input.stream
+	summary := &migrationSummary{kind: "transfer"}
+	batch := make([]models.Transfer, 0, batchSize)
+	// positions holds the input index of each transfer in the batch, which
+	// differ from their batch positions once invalid rows are skipped.
+	positions := make([]int, 0, batchSize)
+	// ends holds the input offset just past each transfer in the batch, for
+	// checkpointing when the tail of the batch is held back.
+	ends := make([]int64, 0, batchSize)
+	linked := tbTypes.TransferFlags{Linked: true}.ToUint16()
+
+	// submit creates the first n transfers of the batch and keeps the rest
+	// for the next one.
+	submit := func(n int) error {
+		start, end := positions[0], positions[n-1]
+
+		var failed tigerbeetle.TransferErrors
+		err := t.client.CreateTransfers(batch[:n])
+		if errors.As(err, &failed) {
+			failed = failed.Remap(positions[:n])
+			err = failed
+		}
+		if err != nil && (!opts.Idempotent || failed == nil) {
+			return fmt.Errorf("error creating transfers in batch %d-%d: %w", start, end, reportTransferErrors(err, 0))
+		}
+		chains := chainStarts(positions[:n], func(i int) bool { return batch[i].Flags&linked != 0 })
+		summary.add(n, transferEventResults(failed, chains))
+		if err := input.commit(end+1, ends[n-1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Processed transfers %d-%d (%s)\n", start, end, progress(ends[n-1], input.size))
+		batch = batch[:copy(batch, batch[n:])]
+		positions = positions[:copy(positions, positions[n:])]
+		ends = ends[:copy(ends, ends[n:])]
+		return nil
+	}
+
+	for {
+		var transfer models.Transfer
+		err := stream.NextTransfer(&transfer, t.ledgers)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if opts.Idempotent && summary.skip(err) {
+				continue
+			}
+			return fmt.Errorf("error parsing %s: %w", input.format, err)
+		}
+		if transfer.Timestamp != 0 {
+			return timestampSetError("transfer", stream.Index()-1, transfer.ID, transfer.Timestamp)
+		}
+
+		batch = append(batch, transfer)
+		positions = append(positions, stream.Index()-1)
+		ends = append(ends, stream.Offset())
+		if len(batch) == batchSize {
+			// Never split a linked chain across requests: hold back any
+			// chain still open at the end of the batch.
+			n := chainBoundary(len(batch), func(i int) bool { return batch[i].Flags&linked != 0 })
+			if n == 0 {
+				return fmt.Errorf("linked chain starting at index %d is longer than the batch size of %d", positions[0], batchSize)
+			}
+			if err := submit(n); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := submit(len(batch)); err != nil {
+			return err
+		}
+	}
+	if err := input.finish(); err != nil {
+		return err
+	}
+
+	if !opts.Idempotent {
+		fmt.Printf("Successfully migrated %d transfers\n", stream.Index())
+		return nil
+	}
+	summary.print()
+	return summary.err()
+}
+
+// chainBoundary returns the length of the longest prefix of a batch of n
+// records that does not end inside a linked chain. linked reports whether the
+// record at a batch position has the linked flag.
+func chainBoundary(n int, linked func(i int) bool) int {
+	for ; n > 0; n-- {
+		if !linked(n - 1) {
+			return n
+		}
+	}
+	return 0
+}
+
+func openMigrationFile(filename string) (*os.File, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("error reading file: %w", err)
+	}
+	return file, info.Size(), nil
+}
+
+// progress formats how far through the input a migration has read.
+func progress(offset, size int64) string {
+	if size <= 0 {
+		return fmt.Sprintf("byte %d", offset)
+	}
+	return fmt.Sprintf("byte %d of %d, %.1f%%", offset, size, float64(offset)*100/float64(size))
+}
+
+// reportAccountErrors prints every failed account in err, with indexes
+// shifted by offset to match the input, and returns the shifted errors.
+// Other errors are returned unchanged.
+func reportAccountErrors(err error, offset int) error {
+	var failed tigerbeetle.AccountErrors
+	if !errors.As(err, &failed) {
+		return err
+	}
+
+	failed = failed.Offset(offset)
+	for _, r := range failed {
+		fmt.Printf("Account at index %d (ID %s) failed: %s\n", r.Index, models.FormatUint128(r.ID), r.Result)
+	}
+	return failed
+}
+
+// reportTransferErrors is reportAccountErrors for transfers.
+func reportTransferErrors(err error, offset int) error {
+	var failed tigerbeetle.TransferErrors
+	if !errors.As(err, &failed) {
+		return err
+	}
+
+	failed = failed.Offset(offset)
+	for _, r := range failed {
+		fmt.Printf("Transfer at index %d (ID %s) failed: %s\n", r.Index, models.FormatUint128(r.ID), r.Result)
+	}
+	return failed
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// openingBalances sets the posted balances of migrated accounts. TigerBeetle
+// creates accounts with zero balances, so each balance from the file is
+// posted as a transfer against the equity account of the account's ledger
+// once the account exists.
+type openingBalances struct {
+	t    *TigerBeagle
+	opts MigrateOptions
+	// equity caches the checked equity account of each ledger.
+	equity map[uint32]tbTypes.Uint128
+}
+
+func newOpeningBalances(t *TigerBeagle, opts MigrateOptions) *openingBalances {
+	return &openingBalances{t: t, opts: opts, equity: make(map[uint32]tbTypes.Uint128)}
+}
+
+// take moves the balances of account into the returned copy, leaving the
+// account with the zero balances the cluster requires. Pending balances
+// cannot be opened with posted transfers and are rejected.
+func (o *openingBalances) take(account *models.Account, index int) (models.Account, error) {
+	zero := tbTypes.Uint128{}
+	if account.DebitsPending != zero || account.CreditsPending != zero {
+		return models.Account{}, fmt.Errorf("account at index %d: opening balances cannot include pending amounts", index)
+	}
+	opening := *account
+	account.DebitsPosted = zero
+	account.CreditsPosted = zero
+	return opening, nil
+}
+
+// equityID returns the configured equity account for ledger.
+func (o *openingBalances) equityID(ledger uint32) (tbTypes.Uint128, error) {
+	if o.opts.EquityAccount != (tbTypes.Uint128{}) {
+		return o.opts.EquityAccount, nil
+	}
+	configured := o.t.ledgers[ledger].EquityAccount
+	if configured == "" {
+		return tbTypes.Uint128{}, fmt.Errorf("no equity account for ledger %d: set one with --equity-account or ledgers.%d.equity_account", ledger, ledger)
+	}
+	id, err := models.ParseUint128(configured)
+	if err != nil {
+		return id, fmt.Errorf("invalid equity account for ledger %d: %w", ledger, err)
+	}
+	return id, nil
+}
+
+// equityAccount returns the equity account for ledger, checking once that
+// it exists on that ledger.
+func (o *openingBalances) equityAccount(ledger uint32) (tbTypes.Uint128, error) {
+	if id, ok := o.equity[ledger]; ok {
+		return id, nil
+	}
+
+	id, err := o.equityID(ledger)
+	if err != nil {
+		return id, err
+	}
+	accounts, err := o.t.client.LookupAccounts([]tbTypes.Uint128{id})
+	if err != nil {
+		return id, fmt.Errorf("error looking up equity account %s: %w", models.FormatUint128(id), err)
+	}
+	if len(accounts) == 0 {
+		return id, fmt.Errorf("equity account %s for ledger %d does not exist", models.FormatUint128(id), ledger)
+	}
+	if accounts[0].Ledger != ledger {
+		return id, fmt.Errorf("equity account %s is on ledger %d, not ledger %d", models.FormatUint128(id), accounts[0].Ledger, ledger)
+	}
+	o.equity[ledger] = id
+	return id, nil
+}
+
+// prepare checks that every ledger with opening balances in accounts has an
+// equity account, before any of the accounts are created. An equity account
+// that is itself in accounts is only checked for its ledger here, and looked
+// up once the batch has been created.
+func (o *openingBalances) prepare(accounts []models.Account) error {
+	zero := tbTypes.Uint128{}
+	batch := make(map[tbTypes.Uint128]uint32, len(accounts))
+	for _, account := range accounts {
+		batch[account.ID] = account.Ledger
+	}
+
+	for _, account := range accounts {
+		if account.DebitsPosted == zero && account.CreditsPosted == zero {
+			continue
+		}
+		if _, ok := o.equity[account.Ledger]; ok {
+			continue
+		}
+		id, err := o.equityID(account.Ledger)
+		if err != nil {
+			return err
+		}
+		if ledger, ok := batch[id]; ok {
+			if ledger != account.Ledger {
+				return fmt.Errorf("equity account %s is on ledger %d, not ledger %d", models.FormatUint128(id), ledger, account.Ledger)
+			}
+			continue
+		}
+		if _, err := o.equityAccount(account.Ledger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openingTransferID derives the ID of an opening balance transfer from the
+// account, so re-running or resuming a migration finds the transfers it
+// already created instead of posting the balance twice.
+func openingTransferID(accountID tbTypes.Uint128, side string) tbTypes.Uint128 {
+	g, _ := NewReferenceIDGenerator(fmt.Sprintf("opening-balance:%s:%s", models.FormatUint128(accountID), side))
+	return g.NextID()
+}
+
+// post creates the opening balance transfers for accounts, which were read
+// at positions in the input, and checks the resulting balances against the
+// file. Accounts at positions in skip were not created and are left out.
+// It returns the accounts that did not end up with their opening balances.
+func (o *openingBalances) post(accounts []models.Account, positions []int, skip map[int]bool) ([]eventResult, error) {
+	zero := tbTypes.Uint128{}
+	var transfers []models.Transfer
+	// owners holds the batch position of the account each transfer opens.
+	var owners []int
+	for i, account := range accounts {
+		if skip[positions[i]] || (account.DebitsPosted == zero && account.CreditsPosted == zero) {
+			continue
+		}
+		equity, err := o.equityAccount(account.Ledger)
+		if err != nil {
+			return nil, err
+		}
+
+		credit := models.Transfer{
+			ID:              openingTransferID(account.ID, "credit"),
+			DebitAccountID:  equity,
+			CreditAccountID: account.ID,
+			Amount:          account.CreditsPosted,
+			Ledger:          account.Ledger,
+			Code:            account.Code,
+		}
+		debit := models.Transfer{
+			ID:              openingTransferID(account.ID, "debit"),
+			DebitAccountID:  account.ID,
+			CreditAccountID: equity,
+			Amount:          account.DebitsPosted,
+			Ledger:          account.Ledger,
+			Code:            account.Code,
+		}
+		// Post the side the account's balance limit allows first.
+		sides := []models.Transfer{credit, debit}
+		if (tbTypes.Account{Flags: account.Flags}).AccountFlags().CreditsMustNotExceedDebits {
+			sides = []models.Transfer{debit, credit}
+		}
+		for _, transfer := range sides {
+			if transfer.Amount != zero {
+				transfers = append(transfers, transfer)
+				owners = append(owners, i)
+			}
+		}
+	}
+
+	var results []eventResult
+	failed := make(map[int]bool)
+	for start := 0; start < len(transfers); start += batchSize {
+		end := start + batchSize
+		if end > len(transfers) {
+			end = len(transfers)
+		}
+
+		err := o.t.client.CreateTransfers(transfers[start:end])
+		var errs tigerbeetle.TransferErrors
+		if !errors.As(err, &errs) {
+			if err != nil {
+				return nil, fmt.Errorf("error creating opening balance transfers: %w", err)
+			}
+			continue
+		}
+		for _, r := range errs {
+			owner := owners[start+r.Index]
+			if r.Exists() || failed[owner] {
+				continue
+			}
+			failed[owner] = true
+			results = append(results, eventResult{
+				index:  positions[owner],
+				id:     accounts[owner].ID,
+				result: fmt.Sprintf("opening balance transfer %s: %s", models.FormatUint128(r.ID), r.Result),
+			})
+		}
+	}
+
+	// Check every account that was opened against the file.
+	var ids []tbTypes.Uint128
+	expected := make(map[tbTypes.Uint128]int)
+	for _, owner := range owners {
+		id := accounts[owner].ID
+		if _, ok := expected[id]; !ok && !failed[owner] {
+			expected[id] = owner
+			ids = append(ids, id)
+		}
+	}
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		found, err := o.t.client.LookupAccounts(ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("error verifying opening balances: %w", err)
+		}
+		for _, account := range found {
+			owner := expected[account.ID]
+			want := accounts[owner]
+			if account.DebitsPosted != want.DebitsPosted || account.CreditsPosted != want.CreditsPosted {
+				results = append(results, eventResult{
+					index: positions[owner],
+					id:    account.ID,
+					result: fmt.Sprintf("balances do not match the file: debits_posted %s, credits_posted %s, expected %s and %s",
+						models.FormatUint128(account.DebitsPosted), models.FormatUint128(account.CreditsPosted),
+						models.FormatUint128(want.DebitsPosted), models.FormatUint128(want.CreditsPosted)),
+				})
+			}
+			delete(expected, account.ID)
+		}
+	}
+	for _, id := range ids {
+		if owner, ok := expected[id]; ok {
+			results = append(results, eventResult{index: positions[owner], id: id, result: "account not found when verifying opening balances"})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// PendingTransfer reserves amount, in ledger's units, from the debit account
+// for a later PostPending or VoidPending. A zero timeout never expires;
+// otherwise the reservation is voided by the cluster once timeout has passed.
+func (t *TigerBeagle) PendingTransfer(debitAccountID, creditAccountID tbTypes.Uint128, amount string, ledger uint32, code uint16, flags uint16, timeout time.Duration) error {
+	flags |= tbTypes.TransferFlags{Pending: true}.ToUint16()
+	if err := models.ValidateTransferFlags(flags); err != nil {
+		return fmt.Errorf("invalid transfer flags: %w", err)
+	}
+	reserved, err := t.ledgers.ParseAmount(amount, ledger)
+	if err != nil {
+		return fmt.Errorf("invalid amount: %w", err)
+	}
+	seconds, err := timeoutSeconds(timeout)
+	if err != nil {
+		return err
+	}
+
+	transfer := models.Transfer{
+		ID:              t.nextID(),
+		DebitAccountID:  debitAccountID,
+		CreditAccountID: creditAccountID,
+		Amount:          reserved,
+		Timeout:         seconds,
+		Ledger:          ledger,
+		Code:            code,
+		Flags:           flags,
+	}
+
+	if err := t.client.CreateTransfers([]models.Transfer{transfer}); err != nil {
+		return fmt.Errorf("error creating pending transfer: %w", err)
+	}
+
+	expiry := "no timeout"
+	if seconds > 0 {
+		expiry = "expires in " + timeout.String()
+	}
+	fmt.Printf("Pending transfer %s reserved %s from account %s to account %s (Ledger: %d, Code: %d, Flags: %s, %s)\n",
+		models.FormatUint128(transfer.ID), t.ledgers.FormatUint128Amount(transfer.Amount, ledger),
+		models.FormatUint128(debitAccountID), models.FormatUint128(creditAccountID),
+		ledger, code, strings.Join(models.TransferFlagNames(flags), ","), expiry)
+	return nil
+}
+
+// PostPending posts a pending transfer. amount may be empty to post the full
+// reserved amount, or a smaller amount in the pending transfer's ledger units
+// to post part of it; the remainder is released back to the debit account.
+func (t *TigerBeagle) PostPending(pendingID tbTypes.Uint128, amount string) error {
+	pending, err := t.lookupPending(pendingID)
+	if err != nil {
+		return err
+	}
+
+	reserved := pending.Amount.BigInt()
+	posted := reserved
+	if amount != "" {
+		parsed, err := t.ledgers.ParseAmount(amount, pending.Ledger)
+		if err != nil {
+			return fmt.Errorf("invalid amount: %w", err)
+		}
+		posted = parsed.BigInt()
+		if posted.Sign() == 0 {
+			return fmt.Errorf("invalid amount: must be greater than zero")
+		}
+		if posted.Cmp(&reserved) > 0 {
+			return fmt.Errorf("invalid amount: %s exceeds the reserved %s",
+				t.ledgers.FormatAmount(&posted, pending.Ledger), t.ledgers.FormatAmount(&reserved, pending.Ledger))
+		}
+	}
+
+	transfer := t.resolvePending(pending, tbTypes.TransferFlags{PostPendingTransfer: true}.ToUint16())
+	transfer.Amount = tbTypes.BigIntToUint128(posted)
+	if err := t.client.CreateTransfers([]models.Transfer{transfer}); err != nil {
+		return fmt.Errorf("error posting pending transfer: %w", err)
+	}
+
+	released := new(big.Int).Sub(&reserved, &posted)
+	fmt.Printf("Transfer %s posted %s of pending transfer %s (reserved %s, released %s)\n",
+		models.FormatUint128(transfer.ID), t.ledgers.FormatAmount(&posted, pending.Ledger), models.FormatUint128(pendingID),
+		t.ledgers.FormatAmount(&reserved, pending.Ledger), t.ledgers.FormatAmount(released, pending.Ledger))
+	return nil
+}
+
+// VoidPending voids a pending transfer, releasing the full reserved amount
+// back to the debit account.
+func (t *TigerBeagle) VoidPending(pendingID tbTypes.Uint128) error {
+	pending, err := t.lookupPending(pendingID)
+	if err != nil {
+		return err
+	}
+
+	transfer := t.resolvePending(pending, tbTypes.TransferFlags{VoidPendingTransfer: true}.ToUint16())
+	if err := t.client.CreateTransfers([]models.Transfer{transfer}); err != nil {
+		return fmt.Errorf("error voiding pending transfer: %w", err)
+	}
+
+	fmt.Printf("Transfer %s voided pending transfer %s (reserved %s, released %s)\n",
+		models.FormatUint128(transfer.ID), models.FormatUint128(pendingID),
+		t.ledgers.FormatUint128Amount(pending.Amount, pending.Ledger), t.ledgers.FormatUint128Amount(pending.Amount, pending.Ledger))
+	return nil
+}
+
+// lookupPending fetches the transfer with the given ID and checks that it
+// was created as a pending transfer.
+func (t *TigerBeagle) lookupPending(pendingID tbTypes.Uint128) (models.Transfer, error) {
+	transfers, err := t.client.LookupTransfers([]tbTypes.Uint128{pendingID})
+	if err != nil {
+		return models.Transfer{}, fmt.Errorf("error fetching pending transfer: %w", err)
+	}
+	if len(transfers) == 0 {
+		return models.Transfer{}, fmt.Errorf("pending transfer %s not found", models.FormatUint128(pendingID))
+	}
+	pending := transfers[0]
+	if pending.Flags&(tbTypes.TransferFlags{Pending: true}).ToUint16() == 0 {
+		return models.Transfer{}, fmt.Errorf("transfer %s is not a pending transfer", models.FormatUint128(pendingID))
+	}
+	return pending, nil
+}
+
+// resolvePending builds a transfer that posts or voids pending, copying the
+// fields the cluster requires to match.
+func (t *TigerBeagle) resolvePending(pending models.Transfer, flags uint16) models.Transfer {
+	return models.Transfer{
+		ID:              t.nextID(),
+		DebitAccountID:  pending.DebitAccountID,
+		CreditAccountID: pending.CreditAccountID,
+		Amount:          pending.Amount,
+		PendingID:       pending.ID,
+		Ledger:          pending.Ledger,
+		Code:            pending.Code,
+		Flags:           flags,
+	}
+}
+
+// timeoutSeconds converts a pending transfer timeout to the whole seconds
+// TigerBeetle stores.
+func timeoutSeconds(timeout time.Duration) (uint32, error) {
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %s: must not be negative", timeout)
+	}
+	if timeout%time.Second != 0 {
+		return 0, fmt.Errorf("invalid timeout %s: must be a whole number of seconds", timeout)
+	}
+	seconds := timeout / time.Second
+	if seconds > 1<<32-1 {
+		return 0, fmt.Errorf("invalid timeout %s: exceeds the maximum of %d seconds", timeout, uint32(1<<32-1))
+	}
+	return uint32(seconds), nil
+}
@@ -0,0 +1,513 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// Problem types reported by a dry run, in the order they are summarised.
+const (
+	problemInvalidRecord  = "invalid_record"
+	problemInvalidFlags   = "invalid_flags"
+	problemZeroID         = "zero_id"
+	problemDuplicateID    = "duplicate_id"
+	problemZeroAmount     = "zero_amount"
+	problemZeroLedger     = "zero_ledger"
+	problemZeroCode       = "zero_code"
+	problemBalanceSet     = "nonzero_balance"
+	problemSameAccount    = "same_account"
+	problemUnknownAccount = "unknown_account"
+	problemLedgerMismatch = "ledger_mismatch"
+	problemOpenChain      = "open_chain"
+	problemTimestampSet   = "nonzero_timestamp"
+
+	// Problems only checked for historical imports.
+	problemMissingTimestamp = "missing_timestamp"
+	problemFutureTimestamp  = "future_timestamp"
+	problemTimestampOrder   = "timestamp_order"
+	problemAccountTimestamp = "account_timestamp"
+)
+
+var problemTypes = []string{
+	problemInvalidRecord, problemInvalidFlags, problemZeroID, problemDuplicateID, problemZeroAmount,
+	problemZeroLedger, problemZeroCode, problemBalanceSet, problemSameAccount, problemUnknownAccount,
+	problemLedgerMismatch, problemOpenChain, problemTimestampSet,
+	problemMissingTimestamp, problemFutureTimestamp, problemTimestampOrder, problemAccountTimestamp,
+}
+
+// problem is one finding of a dry run. where locates the record, and is
+// empty when the detail already does. Flags are validated as records are
+// parsed, so invalid flags are found with the records that fail to parse.
+type problem struct {
+	index  int
+	kind   string
+	where  string
+	detail string
+}
+
+// validationReport collects the problems a dry run finds in a migration
+// file.
+type validationReport struct {
+	kind     string
+	checked  int
+	problems []problem
+	counts   map[string]int
+	// reordered counts records an import would sort into timestamp order.
+	reordered int
+}
+
+func newValidationReport(kind string) *validationReport {
+	return &validationReport{kind: kind, counts: make(map[string]int)}
+}
+
+func (r *validationReport) add(kind string, index int, id tbTypes.Uint128, format string, args ...interface{}) {
+	where := fmt.Sprintf("%s at index %d (ID %s)", r.kind, index, models.FormatUint128(id))
+	r.problems = append(r.problems, problem{index, kind, where, fmt.Sprintf(format, args...)})
+	r.counts[kind]++
+}
+
+// invalid records a record that could not be parsed.
+func (r *validationReport) invalid(index int, err error) {
+	kind := problemInvalidRecord
+	var flagsErr *models.FlagsError
+	if errors.As(err, &flagsErr) {
+		kind = problemInvalidFlags
+	}
+	r.problems = append(r.problems, problem{index: index, kind: kind, detail: err.Error()})
+	r.counts[kind]++
+}
+
+// reorder records the order in which a batch would be imported.
+func (r *validationReport) reorder(order []int) {
+	for i, j := range order {
+		if i != j {
+			r.reordered++
+		}
+	}
+}
+
+func (r *validationReport) print(filename string) {
+	fmt.Printf("Dry run of %s: checked %d %ss, found %d problems\n", filename, r.checked, r.kind, len(r.problems))
+	if r.reordered > 0 {
+		fmt.Printf("Import would sort %d %ss into timestamp order within their batch\n", r.reordered, r.kind)
+	}
+	if len(r.problems) == 0 {
+		return
+	}
+	fmt.Println("Problems by type:")
+	for _, kind := range problemTypes {
+		if n := r.counts[kind]; n > 0 {
+			fmt.Printf("  %-18s %d\n", kind, n)
+		}
+	}
+	// Transfers are checked a batch at a time, after the records that failed
+	// to parse have already been added.
+	sort.SliceStable(r.problems, func(i, j int) bool {
+		return r.problems[i].index < r.problems[j].index
+	})
+	fmt.Println("Problems:")
+	for _, p := range r.problems {
+		if p.where == "" {
+			fmt.Printf("  [%s] %s\n", p.kind, p.detail)
+		} else {
+			fmt.Printf("  [%s] %s: %s\n", p.kind, p.where, p.detail)
+		}
+	}
+}
+
+func (r *validationReport) err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("dry run found %d problems in %d %ss", len(r.problems), r.checked, r.kind)
+}
+
+// readRecords calls next for every record of a migration input. Records that
+// cannot be parsed are added to the report, and reading continues past them
+// as long as the stream could consume them.
+func readRecords(stream recordSource, format string, report *validationReport, next func() error) error {
+	for {
+		index := stream.Index()
+		err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if stream.Index() == index {
+			return fmt.Errorf("error parsing %s: %w", format, err)
+		}
+		report.checked++
+		if err != nil {
+			report.invalid(index, err)
+		}
+	}
+}
+
+// validateAccounts is the dry run of MigrateAccounts.
+func (t *TigerBeagle) validateAccounts(filename string, opts MigrateOptions) error {
+	file, _, err := openMigrationFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stream, err := openRecordSource(file, opts)
+	if err != nil {
+		return err
+	}
+	report := newValidationReport("account")
+	seen := make(map[tbTypes.Uint128]int)
+	linked := tbTypes.AccountFlags{Linked: true}.ToUint16()
+	chainStart, chainID := -1, tbTypes.Uint128{}
+	var clock *importClock
+	if opts.Import {
+		clock = newImportClock()
+	}
+
+	batch := make([]models.Account, 0, batchSize)
+	positions := make([]int, 0, batchSize)
+
+	// check validates the first n accounts of the batch, in timestamp order
+	// for an import, and keeps the rest.
+	check := func(n int) {
+		order := batchOrder(n)
+		if clock != nil {
+			order = timestampOrder(n,
+				func(i int) uint64 { return batch[i].Timestamp },
+				func(i int) bool { return batch[i].Flags&linked != 0 })
+			report.reorder(order)
+		}
+
+		for _, i := range order {
+			account, index := batch[i], positions[i]
+			if account.ID == (tbTypes.Uint128{}) {
+				report.add(problemZeroID, index, account.ID, "id is zero")
+			} else if first, ok := seen[account.ID]; ok {
+				report.add(problemDuplicateID, index, account.ID, "same ID as index %d", first)
+			} else {
+				seen[account.ID] = index
+			}
+			if clock != nil {
+				clock.check(report, index, account.ID, account.Timestamp)
+			} else if account.Timestamp != 0 {
+				report.add(problemTimestampSet, index, account.ID, "timestamp %d must be zero outside an import", account.Timestamp)
+			}
+			checkAccountFields(report, index, account, opts.OpeningBalances)
+		}
+		batch = batch[:copy(batch, batch[n:])]
+		positions = positions[:copy(positions, positions[n:])]
+	}
+
+	err = readRecords(stream, inputFormat(opts), report, func() error {
+		var account models.Account
+		if err := stream.NextAccount(&account, t.ledgers); err != nil {
+			return err
+		}
+		index := stream.Index() - 1
+
+		if account.Flags&linked == 0 {
+			chainStart = -1
+		} else if chainStart < 0 {
+			chainStart, chainID = index, account.ID
+		}
+
+		batch = append(batch, account)
+		positions = append(positions, index)
+		if len(batch) == batchSize {
+			// Keep linked chains within a batch, as a migration does.
+			n := chainBoundary(len(batch), func(i int) bool { return batch[i].Flags&linked != 0 })
+			if n == 0 {
+				n = len(batch)
+			}
+			check(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		check(len(batch))
+	}
+	if chainStart >= 0 {
+		report.add(problemOpenChain, chainStart, chainID, "linked chain is still open at the end of the file")
+	}
+
+	report.print(filename)
+	return report.err()
+}
+
+// checkAccountFields adds the problems with an account's ledger, code and
+// balances to report. Posted balances are allowed when they are to be set as
+// opening balances; pending balances never are.
+func checkAccountFields(report *validationReport, index int, account models.Account, openingBalances bool) {
+	zero := tbTypes.Uint128{}
+
+	if account.Ledger == 0 {
+		report.add(problemZeroLedger, index, account.ID, "ledger is zero")
+	}
+	if account.Code == 0 {
+		report.add(problemZeroCode, index, account.ID, "code is zero")
+	}
+	if account.DebitsPending != zero || account.CreditsPending != zero {
+		report.add(problemBalanceSet, index, account.ID, "pending balances must be zero")
+	}
+	if !openingBalances && (account.DebitsPosted != zero || account.CreditsPosted != zero) {
+		report.add(problemBalanceSet, index, account.ID, "posted balances must be zero without --opening-balances")
+	}
+}
+
+// batchOrder returns the indexes of a batch of n records in input order.
+func batchOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// accountLedgers resolves account IDs to their ledgers and timestamps, from
+// an accounts file and then from the cluster. IDs are looked up in the
+// cluster in batches, and each ID at most once.
+type accountLedgers struct {
+	client     tigerbeetle.Client
+	ledgers    map[tbTypes.Uint128]uint32
+	timestamps map[tbTypes.Uint128]uint64
+	missing    map[tbTypes.Uint128]bool
+}
+
+func (a *accountLedgers) add(account models.Account) {
+	a.ledgers[account.ID] = account.Ledger
+	a.timestamps[account.ID] = account.Timestamp
+}
+
+// loadAccountsFile adds the accounts of a JSON accounts file.
+func (a *accountLedgers) loadAccountsFile(filename string, ledgers models.LedgerRegistry) error {
+	file, _, err := openMigrationFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stream := models.NewRecordStream(file)
+	for {
+		var account models.Account
+		err := stream.NextAccount(&account, ledgers)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading accounts file: %w", err)
+		}
+		a.add(account)
+	}
+}
+
+// resolve looks up every ID in ids that is not known yet.
+func (a *accountLedgers) resolve(ids []tbTypes.Uint128) error {
+	var lookup []tbTypes.Uint128
+	queued := make(map[tbTypes.Uint128]bool)
+	for _, id := range ids {
+		if _, ok := a.ledgers[id]; ok || a.missing[id] || queued[id] || id == (tbTypes.Uint128{}) {
+			continue
+		}
+		queued[id] = true
+		lookup = append(lookup, id)
+	}
+
+	for len(lookup) > 0 {
+		n := len(lookup)
+		if n > batchSize {
+			n = batchSize
+		}
+		accounts, err := a.client.LookupAccounts(lookup[:n])
+		if err != nil {
+			return fmt.Errorf("error looking up accounts: %w", err)
+		}
+		for _, account := range accounts {
+			a.add(account)
+		}
+		for _, id := range lookup[:n] {
+			if _, ok := a.ledgers[id]; !ok {
+				a.missing[id] = true
+			}
+		}
+		lookup = lookup[n:]
+	}
+	return nil
+}
+
+// validateTransfers is the dry run of MigrateTransfers. Accounts are looked
+// up in the cluster, but nothing is created.
+func (t *TigerBeagle) validateTransfers(filename string, opts MigrateOptions) error {
+	accounts := &accountLedgers{
+		client:     t.client,
+		ledgers:    make(map[tbTypes.Uint128]uint32),
+		timestamps: make(map[tbTypes.Uint128]uint64),
+		missing:    make(map[tbTypes.Uint128]bool),
+	}
+	if opts.AccountsFile != "" {
+		if err := accounts.loadAccountsFile(opts.AccountsFile, t.ledgers); err != nil {
+			return err
+		}
+	}
+
+	file, _, err := openMigrationFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stream, err := openRecordSource(file, opts)
+	if err != nil {
+		return err
+	}
+	report := newValidationReport("transfer")
+	seen := make(map[tbTypes.Uint128]int)
+	linked := tbTypes.TransferFlags{Linked: true}.ToUint16()
+	chainStart, chainID := -1, tbTypes.Uint128{}
+	var clock *importClock
+	if opts.Import {
+		clock = newImportClock()
+	}
+
+	batch := make([]models.Transfer, 0, batchSize)
+	positions := make([]int, 0, batchSize)
+
+	// check validates the first n transfers of the batch once their accounts
+	// are resolved, in timestamp order for an import, and keeps the rest.
+	check := func(n int) error {
+		ids := make([]tbTypes.Uint128, 0, 2*n)
+		for _, transfer := range batch[:n] {
+			ids = append(ids, transfer.DebitAccountID, transfer.CreditAccountID)
+		}
+		if err := accounts.resolve(ids); err != nil {
+			return err
+		}
+
+		order := batchOrder(n)
+		if clock != nil {
+			order = timestampOrder(n,
+				func(i int) uint64 { return batch[i].Timestamp },
+				func(i int) bool { return batch[i].Flags&linked != 0 })
+			report.reorder(order)
+		}
+		for _, i := range order {
+			checkTransfer(report, positions[i], batch[i], seen, accounts, clock)
+		}
+		batch = batch[:copy(batch, batch[n:])]
+		positions = positions[:copy(positions, positions[n:])]
+		return nil
+	}
+
+	err = readRecords(stream, inputFormat(opts), report, func() error {
+		var transfer models.Transfer
+		if err := stream.NextTransfer(&transfer, t.ledgers); err != nil {
+			return err
+		}
+		index := stream.Index() - 1
+
+		if transfer.Flags&linked == 0 {
+			chainStart = -1
+		} else if chainStart < 0 {
+			chainStart, chainID = index, transfer.ID
+		}
+
+		batch = append(batch, transfer)
+		positions = append(positions, index)
+		if len(batch) == batchSize {
+			// Keep linked chains within a batch, as a migration does.
+			n := chainBoundary(len(batch), func(i int) bool { return batch[i].Flags&linked != 0 })
+			if n == 0 {
+				n = len(batch)
+			}
+			return check(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		if err := check(len(batch)); err != nil {
+			return err
+		}
+	}
+	if chainStart >= 0 {
+		report.add(problemOpenChain, chainStart, chainID, "linked chain is still open at the end of the file")
+	}
+
+	report.print(filename)
+	return report.err()
+}
+
+// checkTransfer adds the problems with one transfer to report. clock is nil
+// unless the transfer is to be imported.
+func checkTransfer(report *validationReport, index int, transfer models.Transfer, seen map[tbTypes.Uint128]int, accounts *accountLedgers, clock *importClock) {
+	zero := tbTypes.Uint128{}
+
+	if transfer.ID == zero {
+		report.add(problemZeroID, index, transfer.ID, "id is zero")
+	} else if first, ok := seen[transfer.ID]; ok {
+		report.add(problemDuplicateID, index, transfer.ID, "same ID as index %d", first)
+	} else {
+		seen[transfer.ID] = index
+	}
+	if clock != nil {
+		clock.check(report, index, transfer.ID, transfer.Timestamp)
+	} else if transfer.Timestamp != 0 {
+		report.add(problemTimestampSet, index, transfer.ID, "timestamp %d must be zero outside an import", transfer.Timestamp)
+	}
+
+	// Posting or voiding a pending transfer takes the accounts and ledger,
+	// and for a zero amount the full amount, from the pending transfer.
+	f := tbTypes.Transfer{Flags: transfer.Flags}.TransferFlags()
+	if f.PostPendingTransfer || f.VoidPendingTransfer {
+		if transfer.PendingID == zero {
+			report.add(problemZeroID, index, transfer.ID, "pending_id is zero")
+		}
+		if transfer.DebitAccountID == zero && transfer.CreditAccountID == zero {
+			return
+		}
+	} else {
+		if transfer.Amount == zero {
+			report.add(problemZeroAmount, index, transfer.ID, "amount is zero")
+		}
+		if transfer.Ledger == 0 {
+			report.add(problemZeroLedger, index, transfer.ID, "ledger is zero")
+		}
+	}
+
+	if transfer.DebitAccountID != zero && transfer.DebitAccountID == transfer.CreditAccountID {
+		report.add(problemSameAccount, index, transfer.ID, "debit and credit account are both %s", models.FormatUint128(transfer.DebitAccountID))
+	}
+	for _, side := range []struct {
+		name string
+		id   tbTypes.Uint128
+	}{
+		{"debit", transfer.DebitAccountID},
+		{"credit", transfer.CreditAccountID},
+	} {
+		if side.id == zero {
+			report.add(problemZeroID, index, transfer.ID, "%s_account_id is zero", side.name)
+			continue
+		}
+		ledger, ok := accounts.ledgers[side.id]
+		if !ok {
+			report.add(problemUnknownAccount, index, transfer.ID, "%s account %s does not exist", side.name, models.FormatUint128(side.id))
+			continue
+		}
+		// A post or void may leave the ledger zero to inherit it.
+		if transfer.Ledger != 0 && ledger != transfer.Ledger {
+			report.add(problemLedgerMismatch, index, transfer.ID, "transfer is on ledger %d but %s account %s is on ledger %d", transfer.Ledger, side.name, models.FormatUint128(side.id), ledger)
+		}
+		if clock != nil && transfer.Timestamp != 0 && accounts.timestamps[side.id] >= transfer.Timestamp {
+			report.add(problemAccountTimestamp, index, transfer.ID, "timestamp %d is not after %s account %s (timestamp %d)", transfer.Timestamp, side.name, models.FormatUint128(side.id), accounts.timestamps[side.id])
+		}
+	}
+}
@@ -2,11 +2,17 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
 func newCreateAccountCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
@@ -21,12 +27,51 @@ func newCreateAccountCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
 			}
 			ledger := viper.GetUint32("ledger")
 			code := uint16(viper.GetUint32("code"))
-			flags := uint16(viper.GetUint32("flags"))
+			flags, err := accountFlags()
+			if err != nil {
+				return err
+			}
 			return tigerBeagle.CreateAccount(id, ledger, code, flags)
 		},
 	}
 }
 
+func newCreateAccountsCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var fromFile string
+
+	cmd := &cobra.Command{
+		Use:   "create-accounts [id | first..last]...",
+		Short: "Create many accounts from ID ranges or a file",
+		Long: `Create an account for every given ID, with the ledger, code and flags from
+the persistent flags. IDs are given as arguments, each a single ID or an
+inclusive range such as 1000..1999, or with --from-file as one ID or range per
+line (blank lines and lines starting with # are skipped).
+
+Accounts are created in batches. Each ID is reported as created, already
+existing or failed; consecutive created IDs are reported as a range.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ledger := viper.GetUint32("ledger")
+			code := uint16(viper.GetUint32("code"))
+			flags, err := accountFlags()
+			if err != nil {
+				return err
+			}
+
+			ids, closeIDs, err := idSource(args, fromFile)
+			if err != nil {
+				return err
+			}
+			defer closeIDs()
+
+			return tigerBeagle.CreateAccounts(ids, ledger, code, flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "File with one ID or range per line")
+
+	return cmd
+}
+
 func newGetAccountCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
 	return &cobra.Command{
 		Use:   "get-account <account_number>",
@@ -41,19 +86,151 @@ func newGetAccountCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Account details: %+v\n", account)
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+			printAccount(cmd.OutOrStdout(), account, ledgers)
 			return nil
 		},
 	}
 }
 
+func newGetAccountsCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var fromFile string
+
+	cmd := &cobra.Command{
+		Use:   "get-accounts [id | first..last]...",
+		Short: "Look up many accounts and print them as a table",
+		Long: `Look up accounts in batches and print them as a table with decimal IDs,
+named flags and formatted balances. IDs are given as arguments, each a single
+ID or an inclusive range such as 1000..1999, or with --from-file as one ID or
+range per line. IDs that do not exist are listed after the table.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, closeIDs, err := idSource(args, fromFile)
+			if err != nil {
+				return err
+			}
+			defer closeIDs()
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(table, "ID\tLEDGER\tCODE\tFLAGS\tDEBITS PENDING\tDEBITS POSTED\tCREDITS PENDING\tCREDITS POSTED\tBALANCE")
+
+			var missing []string
+			err = tigerBeagle.GetAccounts(ids, func(id tbTypes.Uint128, ab *models.AccountBalances) error {
+				if ab == nil {
+					missing = append(missing, models.FormatUint128(id))
+					return nil
+				}
+				flags := strings.Join(models.AccountFlagNames(ab.Flags), ",")
+				if flags == "" {
+					flags = "-"
+				}
+				fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					models.FormatUint128(ab.ID), ab.Ledger, ab.Code, flags,
+					ledgers.FormatUint128Amount(ab.DebitsPending, ab.Ledger),
+					ledgers.FormatUint128Amount(ab.DebitsPosted, ab.Ledger),
+					ledgers.FormatUint128Amount(ab.CreditsPending, ab.Ledger),
+					ledgers.FormatUint128Amount(ab.CreditsPosted, ab.Ledger),
+					ledgers.FormatAmount(ab.Balance, ab.Ledger))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := table.Flush(); err != nil {
+				return err
+			}
+
+			if len(missing) > 0 {
+				fmt.Fprintf(out, "Not found (%d): %s\n", len(missing), strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "File with one ID or range per line")
+
+	return cmd
+}
+
+// idSource returns the IDs and ID ranges given as arguments or, with
+// fromFile, listed in that file. The returned function closes the file.
+func idSource(args []string, fromFile string) (app.IDSource, func() error, error) {
+	switch {
+	case fromFile != "" && len(args) > 0:
+		return nil, nil, fmt.Errorf("give IDs as arguments or with --from-file, not both")
+	case fromFile != "":
+		file, err := os.Open(fromFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading file: %w", err)
+		}
+		return app.NewListSource(file), file.Close, nil
+	case len(args) == 0:
+		return nil, nil, fmt.Errorf("no IDs given")
+	}
+
+	ranges := make([]app.IDRange, len(args))
+	for i, arg := range args {
+		r, err := app.ParseIDRange(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		ranges[i] = r
+	}
+	return app.NewRangeSource(ranges), func() error { return nil }, nil
+}
+
 func newMigrateAccountsCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
-	return &cobra.Command{
-		Use:   "migrate-accounts <json_file>",
-		Short: "Migrate accounts from a JSON file",
+	var opts app.MigrateOptions
+	var equity string
+
+	cmd := &cobra.Command{
+		Use:   "migrate-accounts <file>",
+		Short: "Migrate accounts from a JSON or CSV file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return tigerBeagle.MigrateAccounts(args[0])
+			if equity != "" {
+				if !opts.OpeningBalances {
+					return fmt.Errorf("--equity-account requires --opening-balances")
+				}
+				id, err := models.ParseUint128(equity)
+				if err != nil {
+					return fmt.Errorf("invalid equity account %q: %w", equity, err)
+				}
+				opts.EquityAccount = id
+			}
+			return tigerBeagle.MigrateAccounts(args[0], opts)
 		},
 	}
+
+	addMigrateFlags(cmd, &opts)
+	cmd.Flags().BoolVar(&opts.OpeningBalances, "opening-balances", false, "Create accounts with zero balances, then post the balances from the file as transfers against an equity account")
+	cmd.Flags().StringVar(&equity, "equity-account", "", "Equity account for --opening-balances (default is each ledger's equity_account from the config)")
+
+	return cmd
+}
+
+func printAccount(w io.Writer, account *models.Account, ledgers models.LedgerRegistry) {
+	fmt.Fprintf(w, "ID:              %s\n", models.FormatUint128(account.ID))
+	fmt.Fprintf(w, "Ledger:          %d\n", account.Ledger)
+	if info, ok := ledgers[account.Ledger]; ok && info.Currency != "" {
+		fmt.Fprintf(w, "Currency:        %s\n", info.Currency)
+	}
+	fmt.Fprintf(w, "Code:            %d\n", account.Code)
+	fmt.Fprintf(w, "Flags:           %s\n", strings.Join(models.AccountFlagNames(account.Flags), ","))
+	fmt.Fprintf(w, "Debits pending:  %s\n", ledgers.FormatUint128Amount(account.DebitsPending, account.Ledger))
+	fmt.Fprintf(w, "Debits posted:   %s\n", ledgers.FormatUint128Amount(account.DebitsPosted, account.Ledger))
+	fmt.Fprintf(w, "Credits pending: %s\n", ledgers.FormatUint128Amount(account.CreditsPending, account.Ledger))
+	fmt.Fprintf(w, "Credits posted:  %s\n", ledgers.FormatUint128Amount(account.CreditsPosted, account.Ledger))
+	fmt.Fprintf(w, "User data 128:   %s\n", models.FormatUint128(account.UserData128))
+	fmt.Fprintf(w, "User data 64:    %d\n", account.UserData64)
+	fmt.Fprintf(w, "User data 32:    %d\n", account.UserData32)
+	fmt.Fprintf(w, "Timestamp:       %d\n", account.Timestamp)
 }
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newBalanceCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var side string
+
+	cmd := &cobra.Command{
+		Use:   "balance <account_id...>",
+		Short: "Show posted, pending and available balances",
+		Long: `Show the net posted, pending and available balances of one or more accounts.
+
+Balances are signed and computed from the account's normal side: credits minus
+debits for credit-normal accounts, debits minus credits for debit-normal ones.
+By default, accounts flagged credits_must_not_exceed_debits are treated as
+debit-normal and all others as credit-normal.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if side != "auto" && side != "credit" && side != "debit" {
+				return fmt.Errorf("invalid side %q: must be 'auto', 'credit' or 'debit'", side)
+			}
+
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+
+			balances, err := tigerBeagle.GetBalances(ids)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for i, ab := range balances {
+				if ab == nil {
+					fmt.Fprintf(out, "Account %s: not found\n", args[i])
+					continue
+				}
+
+				switch side {
+				case "credit":
+					ab.Side = models.CreditNormal
+					ab.Compute()
+				case "debit":
+					ab.Side = models.DebitNormal
+					ab.Compute()
+				}
+
+				fmt.Fprintf(out, "Account %s (%s-normal): posted %s, pending %s, available %s\n",
+					models.FormatUint128(ab.ID), ab.Side,
+					ledgers.FormatAmount(ab.Balance, ab.Ledger),
+					ledgers.FormatAmount(ab.Pending, ab.Ledger),
+					ledgers.FormatAmount(ab.Available, ab.Ledger))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&side, "side", "auto", "Normal side of the accounts: auto, credit or debit")
+
+	return cmd
+}
@@ -3,14 +3,20 @@ package cli
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kris-hansen/tigerbeagle/internal/app"
 	"github.com/kris-hansen/tigerbeagle/pkg/models"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
 type MockTigerBeagle struct {
@@ -22,11 +28,24 @@ func (m *MockTigerBeagle) ValidateConnectivity() error {
 	return args.Error(0)
 }
 
+func (m *MockTigerBeagle) SetIDGenerator(ids app.IDGenerator) {
+	m.Called(ids)
+}
+
+func (m *MockTigerBeagle) SetConcurrency(n int) {
+	m.Called(n)
+}
+
 func (m *MockTigerBeagle) CreateAccount(id uint64, ledger uint32, code uint16, flags uint16) error {
 	args := m.Called(id, ledger, code, flags)
 	return args.Error(0)
 }
 
+func (m *MockTigerBeagle) CreateAccounts(ids app.IDSource, ledger uint32, code uint16, flags uint16) error {
+	args := m.Called(ids, ledger, code, flags)
+	return args.Error(0)
+}
+
 func (m *MockTigerBeagle) GetAccount(id uint64) (*models.Account, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -35,12 +54,93 @@ func (m *MockTigerBeagle) GetAccount(id uint64) (*models.Account, error) {
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockTigerBeagle) Transfer(debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error {
+func (m *MockTigerBeagle) GetAccounts(ids app.IDSource, visit func(id tbTypes.Uint128, ab *models.AccountBalances) error) error {
+	args := m.Called(ids, visit)
+	if balances, ok := args.Get(0).([]*models.AccountBalances); ok {
+		for _, ab := range balances {
+			id, err := ids.Next()
+			if err != nil {
+				return err
+			}
+			if err := visit(id, ab); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTigerBeagle) GetBalances(ids []tbTypes.Uint128) ([]*models.AccountBalances, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AccountBalances), args.Error(1)
+}
+
+func (m *MockTigerBeagle) LookupTransfers(ids []tbTypes.Uint128) ([]*models.Transfer, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Transfer), args.Error(1)
+}
+
+func (m *MockTigerBeagle) AccountHistory(filter app.HistoryFilter, visit func(*models.Transfer) error) error {
+	args := m.Called(filter, visit)
+	if transfers, ok := args.Get(0).([]*models.Transfer); ok {
+		for _, transfer := range transfers {
+			if err := visit(transfer); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTigerBeagle) BalanceHistory(filter app.HistoryFilter, visit func(*models.AccountBalances) error) error {
+	args := m.Called(filter, visit)
+	if balances, ok := args.Get(0).([]*models.AccountBalances); ok {
+		for _, balance := range balances {
+			if err := visit(balance); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTigerBeagle) Transfer(debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error {
 	args := m.Called(debitAccountID, creditAccountID, amount, ledger, code, flags)
 	return args.Error(0)
 }
 
-func (m *MockTigerBeagle) BulkTransfer(iterations int, debitAccountID, creditAccountID, amount uint64, ledger uint32, code uint16, flags uint16) error {
+func (m *MockTigerBeagle) PendingTransfer(debitAccountID, creditAccountID tbTypes.Uint128, amount string, ledger uint32, code uint16, flags uint16, timeout time.Duration) error {
+	args := m.Called(debitAccountID, creditAccountID, amount, ledger, code, flags, timeout)
+	return args.Error(0)
+}
+
+func (m *MockTigerBeagle) PostPending(pendingID tbTypes.Uint128, amount string) error {
+	args := m.Called(pendingID, amount)
+	return args.Error(0)
+}
+
+func (m *MockTigerBeagle) VoidPending(pendingID tbTypes.Uint128) error {
+	args := m.Called(pendingID)
+	return args.Error(0)
+}
+
+func (m *MockTigerBeagle) Sweep(fromAccountID, toAccountID tbTypes.Uint128, ledger uint32, code uint16) error {
+	args := m.Called(fromAccountID, toAccountID, ledger, code)
+	return args.Error(0)
+}
+
+func (m *MockTigerBeagle) BatchTransfer(filename string) error {
+	args := m.Called(filename)
+	return args.Error(0)
+}
+
+func (m *MockTigerBeagle) BulkTransfer(iterations int, debitAccountID, creditAccountID tbTypes.Uint128, amount uint64, ledger uint32, code uint16, flags uint16) error {
 	args := m.Called(iterations, debitAccountID, creditAccountID, amount, ledger, code, flags)
 	return args.Error(0)
 }
@@ -55,13 +155,13 @@ func (m *MockTigerBeagle) GenerateTransfers(number int, ledger uint32, code uint
 	return args.Error(0)
 }
 
-func (m *MockTigerBeagle) MigrateAccounts(filename string) error {
-	args := m.Called(filename)
+func (m *MockTigerBeagle) MigrateAccounts(filename string, opts app.MigrateOptions) error {
+	args := m.Called(filename, opts)
 	return args.Error(0)
 }
 
-func (m *MockTigerBeagle) MigrateTransfers(filename string) error {
-	args := m.Called(filename)
+func (m *MockTigerBeagle) MigrateTransfers(filename string, opts app.MigrateOptions) error {
+	args := m.Called(filename, opts)
 	return args.Error(0)
 }
 
@@ -157,14 +257,11 @@ func TestNewBulkTransferCmd(t *testing.T) {
 			Short: "Perform multiple transfers in bulk",
 			Args:  cobra.ExactArgs(4),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				debit, err := strconv.ParseUint(args[0], 10, 64)
+				accounts, err := parseIDs(args[:2])
 				if err != nil {
-					return fmt.Errorf("invalid debit account: %w", err)
-				}
-				credit, err := strconv.ParseUint(args[1], 10, 64)
-				if err != nil {
-					return fmt.Errorf("invalid credit account: %w", err)
+					return err
 				}
+				debit, credit := accounts[0], accounts[1]
 				amount, err := strconv.ParseUint(args[2], 10, 64)
 				if err != nil {
 					return fmt.Errorf("invalid amount: %w", err)
@@ -187,7 +284,7 @@ func TestNewBulkTransferCmd(t *testing.T) {
 	cmd := customNewBulkTransferCmd(mockTB)
 
 	// Set up the mock expectation
-	mockTB.On("BulkTransfer", 5, uint64(1000), uint64(2000), uint64(100), uint32(700), uint16(10), uint16(0)).Return(nil).Once()
+	mockTB.On("BulkTransfer", 5, tbTypes.ToUint128(1000), tbTypes.ToUint128(2000), uint64(100), uint32(700), uint16(10), uint16(0)).Return(nil).Once()
 
 	// Set up command arguments
 	args := []string{"1000", "2000", "100", "5"}
@@ -202,3 +299,350 @@ func TestNewBulkTransferCmd(t *testing.T) {
 	// Assert that the mock expectations were met
 	mockTB.AssertExpectations(t)
 }
+
+func TestBalanceCmd(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+	cmd := newBalanceCmd(mockTB)
+
+	balance := models.NewAccountBalances(models.Account{
+		ID:            tbTypes.ToUint128(1),
+		DebitsPosted:  tbTypes.ToUint128(40),
+		CreditsPosted: tbTypes.ToUint128(100),
+	})
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(1), tbTypes.ToUint128(2)}
+	mockTB.On("GetBalances", ids).Return([]*models.AccountBalances{balance, nil}, nil).Once()
+
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"1", "2", "--side", "debit"})
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "Account 1 (debit-normal): posted -60, pending 0, available -60")
+	assert.Contains(t, buf.String(), "Account 2: not found")
+	mockTB.AssertExpectations(t)
+}
+
+func TestIDGeneratorFromFlags(t *testing.T) {
+	ids, err := idGeneratorFromFlags("", "")
+	assert.NoError(t, err)
+	assert.IsType(t, app.ULIDGenerator{}, ids)
+
+	ids, err = idGeneratorFromFlags("0x10", "")
+	assert.NoError(t, err)
+	assert.Equal(t, tbTypes.ToUint128(16), ids.NextID())
+
+	ids, err = idGeneratorFromFlags("", "order-7")
+	assert.NoError(t, err)
+	assert.IsType(t, &app.ReferenceIDGenerator{}, ids)
+
+	_, err = idGeneratorFromFlags("1", "order-7")
+	assert.Error(t, err)
+
+	_, err = idGeneratorFromFlags("0", "")
+	assert.Error(t, err)
+}
+
+func TestParseAmountUsesLedgerRegistry(t *testing.T) {
+	viper.Set("ledgers", map[string]interface{}{
+		"700": map[string]interface{}{"currency": "USD", "scale": 2},
+	})
+	defer viper.Set("ledgers", nil)
+
+	amount, err := parseAmount("12.34", 700)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), amount)
+
+	amount, err = parseAmount("1234", 700)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), amount)
+
+	_, err = parseAmount("12.345", 700)
+	assert.Error(t, err)
+
+	_, err = parseAmount("12.34", 701)
+	assert.Error(t, err)
+}
+
+func TestLedgerRegistryEquityAccount(t *testing.T) {
+	viper.Set("ledgers", map[string]interface{}{
+		"700": map[string]interface{}{"currency": "USD", "scale": 2, "equity_account": "0x2328"},
+	})
+	defer viper.Set("ledgers", nil)
+
+	ledgers, err := ledgerRegistry()
+	assert.NoError(t, err)
+	assert.Equal(t, "0x2328", ledgers[700].EquityAccount)
+
+	viper.Set("ledgers", map[string]interface{}{
+		"700": map[string]interface{}{"equity_account": "cash"},
+	})
+	_, err = ledgerRegistry()
+	assert.ErrorContains(t, err, "ledger 700 has invalid equity_account")
+}
+
+func TestGetTransferCmd(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+	cmd := newGetTransferCmd(mockTB)
+
+	transfer := &models.Transfer{
+		ID:              tbTypes.ToUint128(9),
+		DebitAccountID:  tbTypes.ToUint128(1),
+		CreditAccountID: tbTypes.ToUint128(2),
+		Amount:          tbTypes.ToUint128(500),
+		PendingID:       tbTypes.ToUint128(8),
+		Timeout:         30,
+		Ledger:          700,
+		Code:            10,
+		Flags:           4,
+		Timestamp:       1700000000000000000,
+	}
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(9), tbTypes.ToUint128(10)}
+	mockTB.On("LookupTransfers", ids).Return([]*models.Transfer{transfer, nil}, nil).Once()
+
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"9", "0xa"})
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	output := buf.String()
+	assert.Contains(t, output, "Pending ID:        8")
+	assert.Contains(t, output, "Timeout:           30")
+	assert.Contains(t, output, "Flags:             post_pending_transfer")
+	assert.Contains(t, output, "Timestamp:         1700000000000000000")
+	assert.Contains(t, output, "Transfer 0xa: not found")
+	mockTB.AssertExpectations(t)
+}
+
+func TestHistoryCmd(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+	cmd := newHistoryCmd(mockTB)
+
+	transfers := []*models.Transfer{
+		{ID: tbTypes.ToUint128(20), DebitAccountID: tbTypes.ToUint128(1), CreditAccountID: tbTypes.ToUint128(2), Amount: tbTypes.ToUint128(150), Ledger: 700, Timestamp: 1700000000000000000},
+		{ID: tbTypes.ToUint128(21), DebitAccountID: tbTypes.ToUint128(3), CreditAccountID: tbTypes.ToUint128(1), Amount: tbTypes.ToUint128(75), Ledger: 700, Flags: 2, Timestamp: 1700000001000000000},
+	}
+	filter := app.HistoryFilter{
+		AccountID: tbTypes.ToUint128(1),
+		Since:     1700000000000000000,
+		Credits:   true,
+		Debits:    true,
+		Limit:     10,
+	}
+	mockTB.On("AccountHistory", filter, mock.Anything).Return(transfers, nil).Once()
+
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"1", "--since", "2023-11-14T22:13:20Z", "--debits", "--credits", "--limit", "10"})
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	output := buf.String()
+	assert.Contains(t, output, "2023-11-14T22:13:20Z  20  debit   2  150")
+	assert.Contains(t, output, "2023-11-14T22:13:21Z  21  credit  3  75  pending")
+	assert.Contains(t, output, "2 transfers")
+	mockTB.AssertExpectations(t)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := parseTimestamp("1700000000000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1700000000000000000), ts)
+
+	ts, err = parseTimestamp("2023-11-14T22:13:20.5Z")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1700000000500000000), ts)
+
+	_, err = parseTimestamp("yesterday")
+	assert.Error(t, err)
+}
+
+func TestBalanceHistoryCmdAt(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+	cmd := newBalanceHistoryCmd(mockTB)
+
+	ab := &models.AccountBalances{
+		Account: models.Account{ID: tbTypes.ToUint128(1), Ledger: 700, CreditsPosted: tbTypes.ToUint128(500), DebitsPending: tbTypes.ToUint128(50), Timestamp: 1700000000000000000},
+	}
+	ab.Compute()
+	filter := app.HistoryFilter{
+		AccountID: tbTypes.ToUint128(1),
+		Until:     1700000005000000000,
+		Reverse:   true,
+		Limit:     1,
+	}
+	mockTB.On("BalanceHistory", filter, mock.Anything).Return([]*models.AccountBalances{ab}, nil).Once()
+
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"1", "--at", "1700000005000000000"})
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-11-14T22:13:20Z  posted 500, pending -50, available 450\n", buf.String())
+	mockTB.AssertExpectations(t)
+
+	cmd = newBalanceHistoryCmd(mockTB)
+	cmd.SetArgs([]string{"1", "--at", "1700000005000000000", "--limit", "5"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	assert.Error(t, cmd.Execute())
+}
+
+func TestClientConfig(t *testing.T) {
+	viper.Set("tb_address", "3001, 3002,3003")
+	viper.Set("tb_cluster_id", "0x2a")
+	viper.Set("tb_concurrency", 32)
+	defer func() {
+		viper.Set("tb_address", nil)
+		viper.Set("tb_cluster_id", nil)
+		viper.Set("tb_concurrency", nil)
+	}()
+
+	config, err := clientConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3001", "3002", "3003"}, config.Addresses)
+	assert.Equal(t, tbTypes.ToUint128(42), config.ClusterID)
+	assert.Equal(t, uint(32), config.Concurrency)
+
+	viper.Set("tb_address", []string{"10.0.0.1:3000", "10.0.0.2:3000"})
+	config, err = clientConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:3000", "10.0.0.2:3000"}, config.Addresses)
+
+	viper.Set("tb_cluster_id", "-1")
+	_, err = clientConfig()
+	assert.Error(t, err)
+}
+
+func TestPostAndVoidPendingCmds(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+
+	mockTB.On("SetIDGenerator", app.ULIDGenerator{}).Return()
+	mockTB.On("PostPending", tbTypes.ToUint128(5), "12.50").Return(nil).Once()
+	mockTB.On("PostPending", tbTypes.ToUint128(6), "").Return(nil).Once()
+	mockTB.On("VoidPending", tbTypes.ToUint128(7)).Return(nil).Once()
+
+	cmd := newPostPendingCmd(mockTB)
+	cmd.SetArgs([]string{"5", "12.50"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = newPostPendingCmd(mockTB)
+	cmd.SetArgs([]string{"6"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd = newVoidPendingCmd(mockTB)
+	cmd.SetArgs([]string{"7"})
+	assert.NoError(t, cmd.Execute())
+
+	mockTB.AssertExpectations(t)
+}
+
+func TestSweepCmd(t *testing.T) {
+	mockTB := new(MockTigerBeagle)
+	cmd := newSweepCmd(mockTB)
+
+	viper.Set("ledger", 700)
+	viper.Set("code", 10)
+	defer viper.Set("ledger", nil)
+	defer viper.Set("code", nil)
+
+	mockTB.On("SetIDGenerator", mock.Anything).Return()
+	mockTB.On("Sweep", tbTypes.ToUint128(1001), tbTypes.ToUint128(9000), uint32(700), uint16(10)).Return(nil).Once()
+
+	cmd.SetArgs([]string{"1001", "9000", "--reference", "sweep-2024-06-30"})
+	assert.NoError(t, cmd.Execute())
+
+	// Accounts with 128-bit IDs can be swept too.
+	from, err := models.ParseUint128("0x10000000000000001")
+	assert.NoError(t, err)
+	to, err := models.ParseUint128("123e4567-e89b-12d3-a456-426614174000")
+	assert.NoError(t, err)
+	mockTB.On("Sweep", from, to, uint32(700), uint16(10)).Return(nil).Once()
+
+	cmd = newSweepCmd(mockTB)
+	cmd.SetArgs([]string{"0x10000000000000001", "123e4567-e89b-12d3-a456-426614174000"})
+	assert.NoError(t, cmd.Execute())
+	mockTB.AssertExpectations(t)
+}
+
+func TestCreateAccountsCmd(t *testing.T) {
+	viper.Set("ledger", 700)
+	viper.Set("code", 10)
+	viper.Set("flags", "history")
+	defer viper.Set("ledger", nil)
+	defer viper.Set("code", nil)
+	defer viper.Set("flags", nil)
+
+	mockTB := new(MockTigerBeagle)
+	history := tbTypes.AccountFlags{History: true}.ToUint16()
+	mockTB.On("CreateAccounts", mock.Anything, uint32(700), uint16(10), history).Return(nil).Once()
+
+	cmd := newCreateAccountsCmd(mockTB)
+	cmd.SetArgs([]string{"1000..1999", "5000"})
+	assert.NoError(t, cmd.Execute())
+	mockTB.AssertExpectations(t)
+
+	cmd = newCreateAccountsCmd(mockTB)
+	cmd.SetArgs([]string{"10..1"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	assert.Error(t, cmd.Execute())
+}
+
+func TestGetAccountsCmd(t *testing.T) {
+	viper.Set("ledgers", map[string]interface{}{
+		"700": map[string]interface{}{"currency": "USD", "scale": 2},
+	})
+	defer viper.Set("ledgers", nil)
+
+	mockTB := new(MockTigerBeagle)
+	found := models.NewAccountBalances(models.Account{
+		ID:            tbTypes.ToUint128(1000),
+		Ledger:        700,
+		Code:          10,
+		Flags:         tbTypes.AccountFlags{History: true}.ToUint16(),
+		CreditsPosted: tbTypes.ToUint128(12345),
+		DebitsPosted:  tbTypes.ToUint128(345),
+	})
+	mockTB.On("GetAccounts", mock.Anything, mock.Anything).Return([]*models.AccountBalances{found, nil, nil}, nil).Once()
+
+	buf := new(bytes.Buffer)
+	cmd := newGetAccountsCmd(mockTB)
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"1000..1002"})
+
+	assert.NoError(t, cmd.Execute())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, `^ID\s+LEDGER\s+CODE\s+FLAGS`, lines[0])
+	assert.Regexp(t, `^1000\s+700\s+10\s+history\s+0.00 USD\s+3.45 USD\s+0.00 USD\s+123.45 USD\s+120.00 USD$`, lines[1])
+	assert.Equal(t, "Not found (2): 1001, 1002", lines[2])
+	mockTB.AssertExpectations(t)
+}
+
+func TestLoadCSVMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	spec := `delimiter: ";"
+columns:
+  id: Account Number
+  credits_posted: Opening Balance * 100
+defaults:
+  ledger: 700
+  flags: history
+`
+	assert.NoError(t, os.WriteFile(path, []byte(spec), 0o644))
+
+	mapping, err := loadCSVMapping(path)
+	assert.NoError(t, err)
+	assert.Equal(t, ";", mapping.Delimiter)
+	assert.Equal(t, "Account Number", mapping.Columns["id"])
+	assert.Equal(t, "Opening Balance * 100", mapping.Columns["credits_posted"])
+	assert.Equal(t, "700", mapping.Defaults["ledger"])
+
+	empty := filepath.Join(t.TempDir(), "empty.yaml")
+	assert.NoError(t, os.WriteFile(empty, []byte("defaults:\n  ledger: 700\n"), 0o644))
+	_, err = loadCSVMapping(empty)
+	assert.Error(t, err)
+}
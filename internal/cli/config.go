@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// loadConfig reads the config file at path, or .tigerbeagle.yaml from the
+// working or home directory when path is empty. A missing default config file
+// is not an error.
+func loadConfig(path string) error {
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName(".tigerbeagle")
+		viper.AddConfigPath(".")
+		if home, err := os.UserHomeDir(); err == nil {
+			viper.AddConfigPath(home)
+		}
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if path == "" && errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("error reading config: %w", err)
+	}
+	return nil
+}
+
+// clientConfig builds the TigerBeetle client config from the tb_address,
+// tb_cluster_id and tb_concurrency keys. tb_address may be a comma-separated
+// string or, in a config file, a list of replica addresses.
+func clientConfig() (tigerbeetle.Config, error) {
+	var addresses []string
+	for _, entry := range viper.GetStringSlice("tb_address") {
+		for _, address := range strings.Split(entry, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return tigerbeetle.Config{}, fmt.Errorf("no TigerBeetle address given")
+	}
+
+	clusterID, err := models.ParseUint128(viper.GetString("tb_cluster_id"))
+	if err != nil {
+		return tigerbeetle.Config{}, fmt.Errorf("invalid cluster ID: %w", err)
+	}
+
+	concurrency := viper.GetInt("tb_concurrency")
+	if concurrency <= 0 {
+		return tigerbeetle.Config{}, fmt.Errorf("invalid client concurrency %d: must be positive", concurrency)
+	}
+
+	return tigerbeetle.Config{
+		ClusterID:   clusterID,
+		Addresses:   addresses,
+		Concurrency: uint(concurrency),
+	}, nil
+}
+
+// ledgerRegistry builds the ledger registry from the "ledgers" config key:
+//
+//	ledgers:
+//	  700:
+//	    currency: USD
+//	    scale: 2
+//	    equity_account: 9000
+func ledgerRegistry() (models.LedgerRegistry, error) {
+	var raw map[string]models.LedgerInfo
+	if err := viper.UnmarshalKey("ledgers", &raw); err != nil {
+		return nil, fmt.Errorf("invalid ledgers config: %w", err)
+	}
+
+	ledgers := make(models.LedgerRegistry, len(raw))
+	for key, info := range raw {
+		ledger, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ledgers config: ledger %q is not a uint32", key)
+		}
+		if info.Scale > 38 {
+			return nil, fmt.Errorf("invalid ledgers config: ledger %d has scale %d, maximum is 38", ledger, info.Scale)
+		}
+		if info.EquityAccount != "" {
+			if _, err := models.ParseUint128(info.EquityAccount); err != nil {
+				return nil, fmt.Errorf("invalid ledgers config: ledger %d has invalid equity_account: %w", ledger, err)
+			}
+		}
+		ledgers[uint32(ledger)] = info
+	}
+	return ledgers, nil
+}
+
+// parseAmount parses a command-line amount for ledger. Decimal amounts are
+// converted to minor units using the ledger registry.
+func parseAmount(s string, ledger uint32) (uint64, error) {
+	ledgers, err := ledgerRegistry()
+	if err != nil {
+		return 0, err
+	}
+
+	amount, err := ledgers.ParseAmount(s, ledger)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %w", err)
+	}
+
+	value := amount.BigInt()
+	if !value.IsUint64() {
+		return 0, fmt.Errorf("invalid amount: %s exceeds the maximum of %d minor units", s, uint64(1<<64-1))
+	}
+	return value.Uint64(), nil
+}
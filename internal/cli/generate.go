@@ -23,12 +23,19 @@ func newGenerateCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
 
 			ledger := viper.GetUint32("ledger")
 			code := uint16(viper.GetUint32("code"))
-			flags := uint16(viper.GetUint32("flags"))
 
 			switch generateType {
 			case "account":
+				flags, err := accountFlags()
+				if err != nil {
+					return err
+				}
 				return tigerBeagle.GenerateAccounts(number, ledger, code, flags)
 			case "transfer":
+				flags, err := transferFlags()
+				if err != nil {
+					return err
+				}
 				return tigerBeagle.GenerateTransfers(number, ledger, code, flags)
 			default:
 				return fmt.Errorf("invalid generate type: must be 'account' or 'transfer'")
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var since, until string
+	var filter app.HistoryFilter
+
+	cmd := &cobra.Command{
+		Use:   "history <account_id>",
+		Short: "List the transfers of an account",
+		Long: `List the transfers that debit or credit an account, oldest first.
+
+--since and --until take an RFC 3339 time or a cluster timestamp in
+nanoseconds and are inclusive. Results are fetched page by page, so any number
+of transfers can be listed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+			filter.AccountID = ids[0]
+
+			if filter.Since, err = parseTimestamp(since); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if filter.Until, err = parseTimestamp(until); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			if filter.Until != 0 && filter.Since > filter.Until {
+				return fmt.Errorf("--since must not be after --until")
+			}
+			if filter.Limit < 0 {
+				return fmt.Errorf("--limit must not be negative")
+			}
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			count := 0
+			err = tigerBeagle.AccountHistory(filter, func(transfer *models.Transfer) error {
+				direction, counterparty := "credit", transfer.DebitAccountID
+				if transfer.DebitAccountID == filter.AccountID {
+					direction, counterparty = "debit", transfer.CreditAccountID
+				}
+				fmt.Fprintf(out, "%s  %s  %-6s  %s  %s  %s\n",
+					formatTimestamp(transfer.Timestamp),
+					models.FormatUint128(transfer.ID),
+					direction,
+					models.FormatUint128(counterparty),
+					ledgers.FormatUint128Amount(transfer.Amount, transfer.Ledger),
+					strings.Join(models.TransferFlagNames(transfer.Flags), ","))
+				count++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, "%d transfers\n", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only transfers at or after this time")
+	cmd.Flags().StringVar(&until, "until", "", "Only transfers at or before this time")
+	cmd.Flags().BoolVar(&filter.Debits, "debits", false, "Only transfers that debit the account")
+	cmd.Flags().BoolVar(&filter.Credits, "credits", false, "Only transfers that credit the account")
+	cmd.Flags().BoolVar(&filter.Reverse, "reverse", false, "List newest transfers first")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 0, "Maximum number of transfers to list (0 for all)")
+
+	return cmd
+}
+
+func newBalanceHistoryCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var since, until, at string
+	var filter app.HistoryFilter
+
+	cmd := &cobra.Command{
+		Use:   "balance-history <account_id>",
+		Short: "Show an account's balances over time",
+		Long: `Show the posted, pending and available balances of an account after each
+transfer, oldest first. The account must have been created with the history
+flag.
+
+With --at, show only the balances as they stood at that time, after the last
+transfer at or before it. Times take an RFC 3339 time or a cluster timestamp
+in nanoseconds.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+			filter.AccountID = ids[0]
+
+			if filter.Since, err = parseTimestamp(since); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if filter.Until, err = parseTimestamp(until); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			if filter.Until != 0 && filter.Since > filter.Until {
+				return fmt.Errorf("--since must not be after --until")
+			}
+			if filter.Limit < 0 {
+				return fmt.Errorf("--limit must not be negative")
+			}
+
+			if at != "" {
+				if since != "" || until != "" || filter.Reverse || filter.Limit != 0 {
+					return fmt.Errorf("--at cannot be combined with --since, --until, --reverse or --limit")
+				}
+				if filter.Until, err = parseTimestamp(at); err != nil {
+					return fmt.Errorf("invalid --at: %w", err)
+				}
+				filter.Reverse = true
+				filter.Limit = 1
+			}
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			count := 0
+			err = tigerBeagle.BalanceHistory(filter, func(ab *models.AccountBalances) error {
+				fmt.Fprintf(out, "%s  posted %s, pending %s, available %s\n",
+					formatTimestamp(ab.Timestamp),
+					ledgers.FormatAmount(ab.Balance, ab.Ledger),
+					ledgers.FormatAmount(ab.Pending, ab.Ledger),
+					ledgers.FormatAmount(ab.Available, ab.Ledger))
+				count++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case at != "" && count == 0:
+				fmt.Fprintf(out, "Account %s had no transfers at or before %s\n", args[0], formatTimestamp(filter.Until))
+			case at == "":
+				fmt.Fprintf(out, "%d snapshots\n", count)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only balances at or after this time")
+	cmd.Flags().StringVar(&until, "until", "", "Only balances at or before this time")
+	cmd.Flags().StringVar(&at, "at", "", "Show the balances as of this time")
+	cmd.Flags().BoolVar(&filter.Debits, "debits", false, "Only balances after transfers that debit the account")
+	cmd.Flags().BoolVar(&filter.Credits, "credits", false, "Only balances after transfers that credit the account")
+	cmd.Flags().BoolVar(&filter.Reverse, "reverse", false, "List newest balances first")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 0, "Maximum number of balances to list (0 for all)")
+
+	return cmd
+}
+
+// parseTimestamp parses an RFC 3339 time or a TigerBeetle timestamp in
+// nanoseconds since the Unix epoch. An empty string is zero.
+func parseTimestamp(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ns, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return ns, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither an RFC 3339 time nor a timestamp in nanoseconds", s)
+	}
+	if t.Before(time.Unix(0, 0)) {
+		return 0, fmt.Errorf("%q is before the Unix epoch", s)
+	}
+	return uint64(t.UnixNano()), nil
+}
+
+// formatTimestamp formats a TigerBeetle timestamp as an RFC 3339 UTC time.
+func formatTimestamp(ts uint64) string {
+	return time.Unix(0, int64(ts)).UTC().Format(time.RFC3339Nano)
+}
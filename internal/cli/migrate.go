@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addMigrateFlags registers the flags shared by migrate-accounts and
+// migrate-transfers.
+func addMigrateFlags(cmd *cobra.Command, opts *app.MigrateOptions) {
+	var mapping string
+
+	cmd.Flags().BoolVar(&opts.Idempotent, "idempotent", false, "Treat records that already exist unchanged as applied, so the migration can be re-run")
+	cmd.Flags().StringVar(&mapping, "mapping", "", "Read the input as CSV, mapping columns to fields with this YAML file")
+	cmd.Flags().StringVar(&opts.Checkpoint, "checkpoint", "", "File recording progress after each committed batch (default is the input file with .checkpoint appended)")
+	cmd.Flags().BoolVar(&opts.Resume, "resume", false, "Continue after the last batch recorded in the checkpoint")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Check the file and report problems without creating anything")
+	cmd.Flags().BoolVar(&opts.Import, "import", false, "Check the file's original timestamps for a historical import (needs --dry-run: the tigerbeetle-go 0.15.3 client has no imported flag)")
+
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if opts.DryRun && opts.Resume {
+			return fmt.Errorf("--dry-run cannot be combined with --resume")
+		}
+		if opts.AccountsFile != "" && !opts.DryRun {
+			return fmt.Errorf("--accounts requires --dry-run")
+		}
+		if mapping == "" {
+			return nil
+		}
+		m, err := loadCSVMapping(mapping)
+		if err != nil {
+			return err
+		}
+		opts.Mapping = m
+		return nil
+	}
+}
+
+// loadCSVMapping reads a CSV column mapping file.
+func loadCSVMapping(path string) (*models.CSVMapping, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading mapping: %w", err)
+	}
+
+	var mapping models.CSVMapping
+	if err := v.Unmarshal(&mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping: %w", err)
+	}
+	if len(mapping.Columns) == 0 {
+		return nil, fmt.Errorf("invalid mapping: no columns given")
+	}
+	return &mapping, nil
+}
@@ -1,29 +1,58 @@
 package cli
 
 import (
+	"fmt"
+
 	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/internal/tigerbeetle"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
 func NewRootCommand(tigerBeagle *app.TigerBeagle) *cobra.Command {
+	var configFile string
+
 	rootCmd := &cobra.Command{
 		Use:   "tigerbeagle",
 		Short: "TigerBeagle is a CLI tool for TigerBeetle ledger data management",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return tigerBeagle.InitClient(viper.GetString("tb_address"))
+			if err := loadConfig(configFile); err != nil {
+				return err
+			}
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+			tigerBeagle.SetLedgers(ledgers)
+
+			config, err := clientConfig()
+			if err != nil {
+				return err
+			}
+			return tigerBeagle.InitClient(config)
 		},
 		PersistentPostRun: func(cmd *cobra.Command, args []string) {
 			tigerBeagle.CloseClient()
 		},
 	}
 
-	rootCmd.PersistentFlags().String("tb-address", "3000", "TigerBeetle address")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default is .tigerbeagle.yaml in the working or home directory)")
+	rootCmd.PersistentFlags().String("tb-address", "3000", "TigerBeetle replica addresses, comma-separated")
+	rootCmd.PersistentFlags().String("cluster-id", "0", "TigerBeetle cluster ID")
+	rootCmd.PersistentFlags().Uint("tb-concurrency", tigerbeetle.DefaultConcurrency, "Maximum concurrent requests from the TigerBeetle client")
 	rootCmd.PersistentFlags().Uint32("ledger", 700, "Ledger ID")
 	rootCmd.PersistentFlags().Uint16("code", 10, "Account/Transfer code")
-	rootCmd.PersistentFlags().Uint16("flags", 0, "Account/Transfer flags")
+	rootCmd.PersistentFlags().String("flags", "", "Account/Transfer flags as comma-separated names (e.g. linked,history) or an integer")
 
 	viper.BindPFlag("tb_address", rootCmd.PersistentFlags().Lookup("tb-address"))
+	viper.BindPFlag("tb_cluster_id", rootCmd.PersistentFlags().Lookup("cluster-id"))
+	viper.BindPFlag("tb_concurrency", rootCmd.PersistentFlags().Lookup("tb-concurrency"))
+	viper.BindEnv("tb_address", "TB_ADDRESS")
+	viper.BindEnv("tb_cluster_id", "TB_CLUSTER_ID")
+	viper.BindEnv("tb_concurrency", "TB_CONCURRENCY")
 	viper.BindPFlag("ledger", rootCmd.PersistentFlags().Lookup("ledger"))
 	viper.BindPFlag("code", rootCmd.PersistentFlags().Lookup("code"))
 	viper.BindPFlag("flags", rootCmd.PersistentFlags().Lookup("flags"))
@@ -31,14 +60,24 @@ func NewRootCommand(tigerBeagle *app.TigerBeagle) *cobra.Command {
 	// Account commands
 	rootCmd.AddCommand(
 		newCreateAccountCmd(tigerBeagle),
+		newCreateAccountsCmd(tigerBeagle),
 		newGetAccountCmd(tigerBeagle),
+		newGetAccountsCmd(tigerBeagle),
+		newBalanceCmd(tigerBeagle),
+		newHistoryCmd(tigerBeagle),
+		newBalanceHistoryCmd(tigerBeagle),
 		newMigrateAccountsCmd(tigerBeagle),
 	)
 
 	// Transfer commands
 	rootCmd.AddCommand(
 		newTransferCmd(tigerBeagle),
+		newPostPendingCmd(tigerBeagle),
+		newVoidPendingCmd(tigerBeagle),
 		newBulkTransferCmd(tigerBeagle),
+		newBatchTransferCmd(tigerBeagle),
+		newSweepCmd(tigerBeagle),
+		newGetTransferCmd(tigerBeagle),
 		newMigrateTransfersCmd(tigerBeagle),
 	)
 
@@ -50,3 +89,34 @@ func NewRootCommand(tigerBeagle *app.TigerBeagle) *cobra.Command {
 
 	return rootCmd
 }
+
+// accountFlags parses the persistent --flags value as account flags.
+func accountFlags() (uint16, error) {
+	flags, err := models.ParseAccountFlags(viper.GetString("flags"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid account flags: %w", err)
+	}
+	return flags, nil
+}
+
+// transferFlags parses the persistent --flags value as transfer flags.
+func transferFlags() (uint16, error) {
+	flags, err := models.ParseTransferFlags(viper.GetString("flags"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid transfer flags: %w", err)
+	}
+	return flags, nil
+}
+
+// parseIDs parses 128-bit IDs given as decimal, 0x hex or UUID arguments.
+func parseIDs(args []string) ([]tbTypes.Uint128, error) {
+	ids := make([]tbTypes.Uint128, len(args))
+	for i, arg := range args {
+		id, err := models.ParseUint128(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q: %w", arg, err)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
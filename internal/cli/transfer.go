@@ -2,78 +2,357 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kris-hansen/tigerbeagle/internal/app"
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
 func newTransferCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
-	return &cobra.Command{
+	var id, reference string
+	var pending, balancingDebit, balancingCredit bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
 		Use:   "transfer <debit_account> <credit_account> <amount>",
 		Short: "Transfer funds between accounts",
-		Args:  cobra.ExactArgs(3),
+		Long: `Transfer funds between accounts.
+
+With --pending, the amount is reserved rather than moved, and must later be
+settled with post-pending or released with void-pending. --timeout voids the
+reservation automatically once it has passed.
+
+With --balancing-debit or --balancing-credit, the amount is an upper bound:
+the cluster moves at most the debit account's available credit balance, or at
+most what the credit account can take, and the amount moved is reported.`,
+		Args: cobra.ExactArgs(3),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			debit, err := strconv.ParseUint(args[0], 10, 64)
+			accounts, err := parseIDs(args[:2])
 			if err != nil {
-				return fmt.Errorf("invalid debit account: %w", err)
+				return err
 			}
-			credit, err := strconv.ParseUint(args[1], 10, 64)
+			debit, credit := accounts[0], accounts[1]
+			ledger := viper.GetUint32("ledger")
+			code := uint16(viper.GetUint32("code"))
+			flags, err := transferFlags()
 			if err != nil {
-				return fmt.Errorf("invalid credit account: %w", err)
+				return err
+			}
+			if balancingDebit {
+				flags |= tbTypes.TransferFlags{BalancingDebit: true}.ToUint16()
+			}
+			if balancingCredit {
+				flags |= tbTypes.TransferFlags{BalancingCredit: true}.ToUint16()
 			}
-			amount, err := strconv.ParseUint(args[2], 10, 64)
+			ids, err := idGeneratorFromFlags(id, reference)
 			if err != nil {
-				return fmt.Errorf("invalid amount: %w", err)
+				return err
+			}
+			tigerBeagle.SetIDGenerator(ids)
+
+			if pending {
+				return tigerBeagle.PendingTransfer(debit, credit, args[2], ledger, code, flags, timeout)
+			}
+			if timeout != 0 {
+				return fmt.Errorf("--timeout requires --pending")
+			}
+			amount, err := parseAmount(args[2], ledger)
+			if err != nil {
+				return err
 			}
-			ledger := viper.GetUint32("ledger")
-			code := uint16(viper.GetUint32("code"))
-			flags := uint16(viper.GetUint32("flags"))
 			return tigerBeagle.Transfer(debit, credit, amount, ledger, code, flags)
 		},
 	}
+
+	addIDFlags(cmd, &id, &reference)
+	cmd.Flags().BoolVar(&pending, "pending", false, "Reserve the amount as a pending transfer")
+	cmd.Flags().BoolVar(&balancingDebit, "balancing-debit", false, "Move at most the debit account's available balance, up to the amount")
+	cmd.Flags().BoolVar(&balancingCredit, "balancing-credit", false, "Move at most what the credit account can take, up to the amount")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Void the pending transfer automatically after this long, in whole seconds (e.g. 30s, 5m)")
+
+	return cmd
 }
 
 func newBulkTransferCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
-	return &cobra.Command{
+	var id, reference string
+	var concurrency int
+
+	cmd := &cobra.Command{
 		Use:   "bulk-transfer <debit_account> <credit_account> <amount> <iterations>",
 		Short: "Perform multiple transfers in bulk",
 		Args:  cobra.ExactArgs(4),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			debit, err := strconv.ParseUint(args[0], 10, 64)
+			accounts, err := parseIDs(args[:2])
 			if err != nil {
-				return fmt.Errorf("invalid debit account: %w", err)
+				return err
 			}
-			credit, err := strconv.ParseUint(args[1], 10, 64)
+			debit, credit := accounts[0], accounts[1]
+			iterations, err := strconv.Atoi(args[3])
 			if err != nil {
-				return fmt.Errorf("invalid credit account: %w", err)
+				return fmt.Errorf("invalid number of iterations: %w", err)
 			}
-			amount, err := strconv.ParseUint(args[2], 10, 64)
+
+			ledger := viper.GetUint32("ledger")
+			amount, err := parseAmount(args[2], ledger)
 			if err != nil {
-				return fmt.Errorf("invalid amount: %w", err)
+				return err
 			}
-			iterations, err := strconv.Atoi(args[3])
+			code := uint16(viper.GetUint32("code"))
+			flags, err := transferFlags()
 			if err != nil {
-				return fmt.Errorf("invalid number of iterations: %w", err)
+				return err
+			}
+
+			ids, err := idGeneratorFromFlags(id, reference)
+			if err != nil {
+				return err
+			}
+			tigerBeagle.SetIDGenerator(ids)
+
+			if concurrency < 1 {
+				return fmt.Errorf("invalid concurrency %d: must be at least 1", concurrency)
+			}
+			tigerBeagle.SetConcurrency(concurrency)
+
+			return tigerBeagle.BulkTransfer(iterations, debit, credit, amount, ledger, code, flags)
+		},
+	}
+
+	addIDFlags(cmd, &id, &reference)
+	cmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of batches to keep in flight at once")
+
+	return cmd
+}
+
+func newBatchTransferCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var id, reference string
+
+	cmd := &cobra.Command{
+		Use:   "batch-transfer <json_file>",
+		Short: "Create atomic chains of transfers from a JSON file",
+		Long: `Create transfers from a JSON array or newline-delimited JSON file in which
+each record is either a transfer or a chain of transfers:
+
+  {"chain": [{"id": 1, ...}, {"id": 2, ...}]}
+
+The transfers in a chain succeed or fail together. The linked flag is set on
+every member but the last, chains are never split across requests, and a
+failed chain is reported as a whole. Transfers without an ID are assigned one
+using --id, --reference or time-ordered IDs.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := idGeneratorFromFlags(id, reference)
+			if err != nil {
+				return err
 			}
+			tigerBeagle.SetIDGenerator(ids)
 
+			return tigerBeagle.BatchTransfer(args[0])
+		},
+	}
+
+	addIDFlags(cmd, &id, &reference)
+
+	return cmd
+}
+
+func newSweepCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var id, reference string
+
+	cmd := &cobra.Command{
+		Use:   "sweep <from_account> <to_account>",
+		Short: "Move an account's full available balance to another account",
+		Long: `Move the full available balance of an account to another account.
+
+The sweep is a single balancing transfer: the cluster decides the amount when
+the transfer is applied, so funds arriving or leaving concurrently cannot make
+it overdraw or leave a remainder. The amount actually moved is reported.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			accounts, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
 			ledger := viper.GetUint32("ledger")
 			code := uint16(viper.GetUint32("code"))
-			flags := uint16(viper.GetUint32("flags"))
 
-			return tigerBeagle.BulkTransfer(iterations, debit, credit, amount, ledger, code, flags)
+			ids, err := idGeneratorFromFlags(id, reference)
+			if err != nil {
+				return err
+			}
+			tigerBeagle.SetIDGenerator(ids)
+
+			return tigerBeagle.Sweep(accounts[0], accounts[1], ledger, code)
 		},
 	}
+
+	addIDFlags(cmd, &id, &reference)
+
+	return cmd
 }
 
-func newMigrateTransfersCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
+func newPostPendingCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var id, reference string
+
+	cmd := &cobra.Command{
+		Use:   "post-pending <pending_id> [amount]",
+		Short: "Post a pending transfer",
+		Long: `Post a pending transfer, moving the reserved funds to the credit account.
+
+Without an amount the full reservation is posted. A smaller amount posts part
+of it and releases the rest back to the debit account.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pendingID, err := models.ParseUint128(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid pending id %q: %w", args[0], err)
+			}
+			amount := ""
+			if len(args) == 2 {
+				amount = args[1]
+			}
+
+			ids, err := idGeneratorFromFlags(id, reference)
+			if err != nil {
+				return err
+			}
+			tigerBeagle.SetIDGenerator(ids)
+
+			return tigerBeagle.PostPending(pendingID, amount)
+		},
+	}
+
+	addIDFlags(cmd, &id, &reference)
+
+	return cmd
+}
+
+func newVoidPendingCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
+	var id, reference string
+
+	cmd := &cobra.Command{
+		Use:   "void-pending <pending_id>",
+		Short: "Void a pending transfer",
+		Long:  `Void a pending transfer, releasing the full reservation back to the debit account.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pendingID, err := models.ParseUint128(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid pending id %q: %w", args[0], err)
+			}
+
+			ids, err := idGeneratorFromFlags(id, reference)
+			if err != nil {
+				return err
+			}
+			tigerBeagle.SetIDGenerator(ids)
+
+			return tigerBeagle.VoidPending(pendingID)
+		},
+	}
+
+	addIDFlags(cmd, &id, &reference)
+
+	return cmd
+}
+
+func newGetTransferCmd(tigerBeagle app.TigerBeagleInterface) *cobra.Command {
 	return &cobra.Command{
-		Use:   "migrate-transfers <json_file>",
-		Short: "Migrate transfers from a JSON file",
+		Use:   "get-transfer <transfer_id...>",
+		Short: "Get transfer details",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+
+			ledgers, err := ledgerRegistry()
+			if err != nil {
+				return err
+			}
+
+			transfers, err := tigerBeagle.LookupTransfers(ids)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for i, transfer := range transfers {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				if transfer == nil {
+					fmt.Fprintf(out, "Transfer %s: not found\n", args[i])
+					continue
+				}
+				printTransfer(out, transfer, ledgers)
+			}
+			return nil
+		},
+	}
+}
+
+func printTransfer(w io.Writer, transfer *models.Transfer, ledgers models.LedgerRegistry) {
+	fmt.Fprintf(w, "ID:                %s\n", models.FormatUint128(transfer.ID))
+	fmt.Fprintf(w, "Debit account ID:  %s\n", models.FormatUint128(transfer.DebitAccountID))
+	fmt.Fprintf(w, "Credit account ID: %s\n", models.FormatUint128(transfer.CreditAccountID))
+	fmt.Fprintf(w, "Amount:            %s\n", ledgers.FormatUint128Amount(transfer.Amount, transfer.Ledger))
+	fmt.Fprintf(w, "Pending ID:        %s\n", models.FormatUint128(transfer.PendingID))
+	fmt.Fprintf(w, "User data 128:     %s\n", models.FormatUint128(transfer.UserData128))
+	fmt.Fprintf(w, "User data 64:      %d\n", transfer.UserData64)
+	fmt.Fprintf(w, "User data 32:      %d\n", transfer.UserData32)
+	fmt.Fprintf(w, "Timeout:           %d\n", transfer.Timeout)
+	fmt.Fprintf(w, "Ledger:            %d\n", transfer.Ledger)
+	fmt.Fprintf(w, "Code:              %d\n", transfer.Code)
+	fmt.Fprintf(w, "Flags:             %s\n", strings.Join(models.TransferFlagNames(transfer.Flags), ","))
+	fmt.Fprintf(w, "Timestamp:         %d\n", transfer.Timestamp)
+}
+
+func newMigrateTransfersCmd(tigerBeagle *app.TigerBeagle) *cobra.Command {
+	var opts app.MigrateOptions
+
+	cmd := &cobra.Command{
+		Use:   "migrate-transfers <file>",
+		Short: "Migrate transfers from a JSON or CSV file",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return tigerBeagle.MigrateTransfers(args[0])
+			return tigerBeagle.MigrateTransfers(args[0], opts)
 		},
 	}
+
+	addMigrateFlags(cmd, &opts)
+	cmd.Flags().StringVar(&opts.AccountsFile, "accounts", "", "With --dry-run, treat the accounts in this JSON file as existing")
+
+	return cmd
+}
+
+func addIDFlags(cmd *cobra.Command, id, reference *string) {
+	cmd.Flags().StringVar(id, "id", "", "Transfer ID to use (decimal, 0x hex or UUID); bulk transfers count up from it")
+	cmd.Flags().StringVar(reference, "reference", "", "External reference to derive deterministic transfer IDs from")
+}
+
+// idGeneratorFromFlags picks the transfer ID strategy: a caller-supplied
+// --id, a hash of --reference, or time-ordered IDs when neither is set.
+func idGeneratorFromFlags(id, reference string) (app.IDGenerator, error) {
+	switch {
+	case id != "" && reference != "":
+		return nil, fmt.Errorf("--id and --reference cannot be used together")
+	case id != "":
+		start, err := models.ParseUint128(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+		return app.NewSequentialIDGenerator(start)
+	case reference != "":
+		return app.NewReferenceIDGenerator(reference)
+	default:
+		return app.ULIDGenerator{}, nil
+	}
 }
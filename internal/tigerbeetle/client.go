@@ -8,10 +8,16 @@ import (
 	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
+// Client wraps the TigerBeetle client. CreateAccounts and CreateTransfers
+// return AccountErrors and TransferErrors when events in a batch fail.
 type Client interface {
 	CreateAccounts(accounts []models.Account) error
 	LookupAccount(id uint64) (*models.Account, error)
+	LookupAccounts(ids []tbTypes.Uint128) ([]models.Account, error)
 	CreateTransfers(transfers []models.Transfer) error
+	LookupTransfers(ids []tbTypes.Uint128) ([]models.Transfer, error)
+	GetAccountTransfers(filter tbTypes.AccountFilter) ([]models.Transfer, error)
+	GetAccountBalances(filter tbTypes.AccountFilter) ([]tbTypes.AccountBalance, error)
 	Ping() error
 	Close()
 }
@@ -20,8 +26,28 @@ type tigerbeetleClient struct {
 	client tb.Client
 }
 
-func NewClient(address string) (Client, error) {
-	client, err := tb.NewClient(tbTypes.ToUint128(0), []string{address}, 256)
+// Config selects the cluster a Client connects to.
+type Config struct {
+	ClusterID tbTypes.Uint128
+	// Addresses lists every replica of the cluster.
+	Addresses []string
+	// Concurrency is the maximum number of requests in flight at once.
+	Concurrency uint
+}
+
+// DefaultConcurrency is the client concurrency used when none is configured.
+const DefaultConcurrency = 256
+
+func NewClient(config Config) (Client, error) {
+	if len(config.Addresses) == 0 {
+		return nil, fmt.Errorf("error creating TigerBeetle client: no replica addresses given")
+	}
+	concurrency := config.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	client, err := tb.NewClient(config.ClusterID, config.Addresses, concurrency)
 	if err != nil {
 		return nil, fmt.Errorf("error creating TigerBeetle client: %w", err)
 	}
@@ -39,11 +65,19 @@ func (c *tigerbeetleClient) CreateAccounts(accounts []models.Account) error {
 		return fmt.Errorf("error creating accounts: %w", err)
 	}
 
+	var failed AccountErrors
 	for _, result := range results {
 		if result.Result != tbTypes.AccountOK {
-			return fmt.Errorf("error creating account: %s", result.Result)
+			failed = append(failed, AccountResult{
+				Index:  int(result.Index),
+				ID:     tbAccounts[result.Index].ID,
+				Result: result.Result,
+			})
 		}
 	}
+	if len(failed) > 0 {
+		return failed
+	}
 
 	return nil
 }
@@ -61,6 +95,19 @@ func (c *tigerbeetleClient) LookupAccount(id uint64) (*models.Account, error) {
 	return models.FromTigerBeetleAccount(accounts[0]), nil
 }
 
+func (c *tigerbeetleClient) LookupAccounts(ids []tbTypes.Uint128) ([]models.Account, error) {
+	tbAccounts, err := c.client.LookupAccounts(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up accounts: %w", err)
+	}
+
+	accounts := make([]models.Account, len(tbAccounts))
+	for i, tbAccount := range tbAccounts {
+		accounts[i] = *models.FromTigerBeetleAccount(tbAccount)
+	}
+	return accounts, nil
+}
+
 func (c *tigerbeetleClient) CreateTransfers(transfers []models.Transfer) error {
 	tbTransfers := make([]tbTypes.Transfer, len(transfers))
 	for i, transfer := range transfers {
@@ -72,15 +119,57 @@ func (c *tigerbeetleClient) CreateTransfers(transfers []models.Transfer) error {
 		return fmt.Errorf("error creating transfers: %w", err)
 	}
 
+	var failed TransferErrors
 	for _, result := range results {
 		if result.Result != tbTypes.TransferOK {
-			return fmt.Errorf("error creating transfer: %s", result.Result)
+			failed = append(failed, TransferResult{
+				Index:  int(result.Index),
+				ID:     tbTransfers[result.Index].ID,
+				Result: result.Result,
+			})
 		}
 	}
+	if len(failed) > 0 {
+		return failed
+	}
 
 	return nil
 }
 
+func (c *tigerbeetleClient) LookupTransfers(ids []tbTypes.Uint128) ([]models.Transfer, error) {
+	tbTransfers, err := c.client.LookupTransfers(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up transfers: %w", err)
+	}
+
+	transfers := make([]models.Transfer, len(tbTransfers))
+	for i, tbTransfer := range tbTransfers {
+		transfers[i] = *models.FromTigerBeetleTransfer(tbTransfer)
+	}
+	return transfers, nil
+}
+
+func (c *tigerbeetleClient) GetAccountTransfers(filter tbTypes.AccountFilter) ([]models.Transfer, error) {
+	tbTransfers, err := c.client.GetAccountTransfers(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting account transfers: %w", err)
+	}
+
+	transfers := make([]models.Transfer, len(tbTransfers))
+	for i, tbTransfer := range tbTransfers {
+		transfers[i] = *models.FromTigerBeetleTransfer(tbTransfer)
+	}
+	return transfers, nil
+}
+
+func (c *tigerbeetleClient) GetAccountBalances(filter tbTypes.AccountFilter) ([]tbTypes.AccountBalance, error) {
+	balances, err := c.client.GetAccountBalances(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting account balances: %w", err)
+	}
+	return balances, nil
+}
+
 func (c *tigerbeetleClient) Ping() (err error) {
 	defer func() {
 		if r := recover(); r != nil {
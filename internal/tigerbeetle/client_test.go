@@ -1,6 +1,7 @@
 package tigerbeetle
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -98,3 +99,56 @@ func TestLookupAccount(t *testing.T) {
 	assert.Contains(t, err.Error(), "error looking up account: lookup error")
 	mockTB.AssertExpectations(t)
 }
+
+func TestCreateTransfersReportsEveryFailure(t *testing.T) {
+	mockTB := new(MockTBClient)
+	client := &tigerbeetleClient{client: mockTB}
+
+	mockTB.On("CreateTransfers", mock.Anything).Return([]tbTypes.TransferEventResult{
+		{Index: 1, Result: tbTypes.TransferExceedsCredits},
+		{Index: 2, Result: tbTypes.TransferDebitAccountNotFound},
+	}, nil).Once()
+
+	err := client.CreateTransfers([]models.Transfer{
+		{ID: tbTypes.ToUint128(10)},
+		{ID: tbTypes.ToUint128(11)},
+		{ID: tbTypes.ToUint128(12)},
+	})
+	assert.Error(t, err)
+
+	var failed TransferErrors
+	assert.True(t, errors.As(err, &failed))
+	assert.Equal(t, TransferErrors{
+		{Index: 1, ID: tbTypes.ToUint128(11), Result: tbTypes.TransferExceedsCredits},
+		{Index: 2, ID: tbTypes.ToUint128(12), Result: tbTypes.TransferDebitAccountNotFound},
+	}, failed)
+	assert.Contains(t, err.Error(), "and 1 more")
+	mockTB.AssertExpectations(t)
+}
+
+func TestLookupTransfers(t *testing.T) {
+	mockTB := new(MockTBClient)
+	client := &tigerbeetleClient{client: mockTB}
+
+	ids := []tbTypes.Uint128{tbTypes.ToUint128(1)}
+	mockTB.On("LookupTransfers", ids).Return([]tbTypes.Transfer{{ID: tbTypes.ToUint128(1), Timeout: 30}}, nil).Once()
+	transfers, err := client.LookupTransfers(ids)
+	assert.NoError(t, err)
+	assert.Len(t, transfers, 1)
+	assert.Equal(t, uint32(30), transfers[0].Timeout)
+
+	mockTB.On("LookupTransfers", ids).Return([]tbTypes.Transfer{}, fmt.Errorf("lookup error")).Once()
+	_, err = client.LookupTransfers(ids)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error looking up transfers: lookup error")
+	mockTB.AssertExpectations(t)
+}
+
+func TestErrorsRemap(t *testing.T) {
+	accounts := AccountErrors{{Index: 0}, {Index: 2}}.Remap([]int{5, 7, 9})
+	assert.Equal(t, 5, accounts[0].Index)
+	assert.Equal(t, 9, accounts[1].Index)
+
+	transfers := TransferErrors{{Index: 1}}.Remap([]int{0, 2})
+	assert.Equal(t, 2, transfers[0].Index)
+}
@@ -0,0 +1,150 @@
+package tigerbeetle
+
+import (
+	"fmt"
+
+	"github.com/kris-hansen/tigerbeagle/pkg/models"
+	tbTypes "github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// AccountResult is the outcome of one account event the cluster did not
+// accept. Index is the position of the event in the submitted batch.
+type AccountResult struct {
+	Index  int
+	ID     tbTypes.Uint128
+	Result tbTypes.CreateAccountResult
+}
+
+// AccountErrors is returned by CreateAccounts when one or more events in the
+// batch fail. It lists every failed event, in batch order.
+type AccountErrors []AccountResult
+
+func (e AccountErrors) Error() string {
+	first := e[0]
+	msg := fmt.Sprintf("error creating account: %s at index %d (id %s)", first.Result, first.Index, models.FormatUint128(first.ID))
+	if len(e) > 1 {
+		msg += fmt.Sprintf(" and %d more", len(e)-1)
+	}
+	return msg
+}
+
+// Offset returns a copy of e with every index shifted by n, to relate
+// batch positions back to positions in a larger input.
+func (e AccountErrors) Offset(n int) AccountErrors {
+	shifted := make(AccountErrors, len(e))
+	for i, r := range e {
+		r.Index += n
+		shifted[i] = r
+	}
+	return shifted
+}
+
+// Remap returns a copy of e with every index i replaced by indexes[i], to
+// relate batch positions back to an input from which records were skipped.
+func (e AccountErrors) Remap(indexes []int) AccountErrors {
+	remapped := make(AccountErrors, len(e))
+	for i, r := range e {
+		r.Index = indexes[r.Index]
+		remapped[i] = r
+	}
+	return remapped
+}
+
+// TransferResult is the outcome of one transfer event the cluster did not
+// accept. Index is the position of the event in the submitted batch.
+type TransferResult struct {
+	Index  int
+	ID     tbTypes.Uint128
+	Result tbTypes.CreateTransferResult
+}
+
+// TransferErrors is returned by CreateTransfers when one or more events in
+// the batch fail. It lists every failed event, in batch order.
+type TransferErrors []TransferResult
+
+func (e TransferErrors) Error() string {
+	first := e[0]
+	msg := fmt.Sprintf("error creating transfer: %s at index %d (id %s)", first.Result, first.Index, models.FormatUint128(first.ID))
+	if len(e) > 1 {
+		msg += fmt.Sprintf(" and %d more", len(e)-1)
+	}
+	return msg
+}
+
+// Offset returns a copy of e with every index shifted by n, to relate
+// batch positions back to positions in a larger input.
+func (e TransferErrors) Offset(n int) TransferErrors {
+	shifted := make(TransferErrors, len(e))
+	for i, r := range e {
+		r.Index += n
+		shifted[i] = r
+	}
+	return shifted
+}
+
+// Remap returns a copy of e with every index i replaced by indexes[i], to
+// relate batch positions back to an input from which records were skipped.
+func (e TransferErrors) Remap(indexes []int) TransferErrors {
+	remapped := make(TransferErrors, len(e))
+	for i, r := range e {
+		r.Index = indexes[r.Index]
+		remapped[i] = r
+	}
+	return remapped
+}
+
+// Exists reports whether an identical account already exists.
+func (r AccountResult) Exists() bool {
+	return r.Result == tbTypes.AccountExists
+}
+
+// LinkedEventFailed reports whether the account failed only because another
+// event in its linked chain did.
+func (r AccountResult) LinkedEventFailed() bool {
+	return r.Result == tbTypes.AccountLinkedEventFailed
+}
+
+// Conflict reports whether an account with the same ID but different
+// fields already exists.
+func (r AccountResult) Conflict() bool {
+	switch r.Result {
+	case tbTypes.AccountExistsWithDifferentFlags,
+		tbTypes.AccountExistsWithDifferentUserData128,
+		tbTypes.AccountExistsWithDifferentUserData64,
+		tbTypes.AccountExistsWithDifferentUserData32,
+		tbTypes.AccountExistsWithDifferentLedger,
+		tbTypes.AccountExistsWithDifferentCode:
+		return true
+	}
+	return false
+}
+
+// Exists reports whether an identical transfer already exists.
+func (r TransferResult) Exists() bool {
+	return r.Result == tbTypes.TransferExists
+}
+
+// LinkedEventFailed reports whether the transfer failed only because another
+// event in its linked chain did.
+func (r TransferResult) LinkedEventFailed() bool {
+	return r.Result == tbTypes.TransferLinkedEventFailed
+}
+
+// Conflict reports whether a transfer with the same ID but different
+// fields already exists.
+func (r TransferResult) Conflict() bool {
+	switch r.Result {
+	case tbTypes.TransferExistsWithDifferentFlags,
+		tbTypes.TransferExistsWithDifferentDebitAccountID,
+		tbTypes.TransferExistsWithDifferentCreditAccountID,
+		tbTypes.TransferExistsWithDifferentAmount,
+		tbTypes.TransferExistsWithDifferentPendingID,
+		tbTypes.TransferExistsWithDifferentUserData128,
+		tbTypes.TransferExistsWithDifferentUserData64,
+		tbTypes.TransferExistsWithDifferentUserData32,
+		tbTypes.TransferExistsWithDifferentTimeout,
+		tbTypes.TransferExistsWithDifferentCode:
+		return true
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
@@ -10,7 +11,9 @@ import (
 
 type Account struct {
 	ID             types.Uint128
-	UserID         types.Uint128
+	UserData128    types.Uint128
+	UserData64     uint64
+	UserData32     uint32
 	Ledger         uint32
 	Code           uint16
 	Flags          uint16
@@ -18,6 +21,7 @@ type Account struct {
 	DebitsPosted   types.Uint128
 	CreditsPending types.Uint128
 	CreditsPosted  types.Uint128
+	Timestamp      uint64
 }
 
 // SetID sets the ID of the account using a uint64 value
@@ -27,65 +31,204 @@ func (a *Account) SetID(id uint64) {
 
 func (a Account) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		ID             uint64 `json:"id"`
-		UserID         uint64 `json:"user_id"`
+		ID             string `json:"id"`
+		UserData128    string `json:"user_data_128"`
+		UserData64     uint64 `json:"user_data_64"`
+		UserData32     uint32 `json:"user_data_32"`
 		Ledger         uint32 `json:"ledger"`
 		Code           uint16 `json:"code"`
 		Flags          uint16 `json:"flags"`
-		DebitsPending  uint64 `json:"debits_pending"`
-		DebitsPosted   uint64 `json:"debits_posted"`
-		CreditsPending uint64 `json:"credits_pending"`
-		CreditsPosted  uint64 `json:"credits_posted"`
+		DebitsPending  string `json:"debits_pending"`
+		DebitsPosted   string `json:"debits_posted"`
+		CreditsPending string `json:"credits_pending"`
+		CreditsPosted  string `json:"credits_posted"`
+		Timestamp      uint64 `json:"timestamp"`
 	}{
-		ID:             uint64FromUint128(a.ID),
-		UserID:         uint64FromUint128(a.UserID),
+		ID:             FormatUint128(a.ID),
+		UserData128:    FormatUint128(a.UserData128),
+		UserData64:     a.UserData64,
+		UserData32:     a.UserData32,
 		Ledger:         a.Ledger,
 		Code:           a.Code,
 		Flags:          a.Flags,
-		DebitsPending:  uint64FromUint128(a.DebitsPending),
-		DebitsPosted:   uint64FromUint128(a.DebitsPosted),
-		CreditsPending: uint64FromUint128(a.CreditsPending),
-		CreditsPosted:  uint64FromUint128(a.CreditsPosted),
+		DebitsPending:  FormatUint128(a.DebitsPending),
+		DebitsPosted:   FormatUint128(a.DebitsPosted),
+		CreditsPending: FormatUint128(a.CreditsPending),
+		CreditsPosted:  FormatUint128(a.CreditsPosted),
+		Timestamp:      a.Timestamp,
 	})
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface
+// UnmarshalJSON implements the json.Unmarshaler interface. 128-bit fields
+// accept decimal strings or numbers, "0x" hex strings and UUIDs. The
+// deprecated user_id key is read as user_data_128.
 func (a *Account) UnmarshalJSON(data []byte) error {
+	return a.unmarshalJSON(data, nil)
+}
+
+func (a *Account) unmarshalJSON(data []byte, ledgers LedgerRegistry) error {
 	aux := &struct {
-		ID             uint64 `json:"id"`
-		UserID         uint64 `json:"user_id"`
-		Ledger         uint32 `json:"ledger"`
-		Code           uint16 `json:"code"`
-		Flags          uint16 `json:"flags"`
-		DebitsPending  uint64 `json:"debits_pending"`
-		DebitsPosted   uint64 `json:"debits_posted"`
-		CreditsPending uint64 `json:"credits_pending"`
-		CreditsPosted  uint64 `json:"credits_posted"`
+		ID             json.RawMessage `json:"id"`
+		UserData128    json.RawMessage `json:"user_data_128"`
+		UserID         json.RawMessage `json:"user_id"`
+		UserData64     uint64          `json:"user_data_64"`
+		UserData32     uint32          `json:"user_data_32"`
+		Ledger         uint32          `json:"ledger"`
+		Code           uint16          `json:"code"`
+		Flags          json.RawMessage `json:"flags"`
+		DebitsPending  json.RawMessage `json:"debits_pending"`
+		DebitsPosted   json.RawMessage `json:"debits_posted"`
+		CreditsPending json.RawMessage `json:"credits_pending"`
+		CreditsPosted  json.RawMessage `json:"credits_posted"`
+		Timestamp      uint64          `json:"timestamp"`
 	}{}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	a.ID = types.ToUint128(aux.ID)
-	a.UserID = types.ToUint128(aux.UserID)
+
+	var userID types.Uint128
+	fields := []struct {
+		name   string
+		raw    json.RawMessage
+		dst    *types.Uint128
+		amount bool
+	}{
+		{"id", aux.ID, &a.ID, false},
+		{"user_data_128", aux.UserData128, &a.UserData128, false},
+		{"user_id", aux.UserID, &userID, false},
+		{"debits_pending", aux.DebitsPending, &a.DebitsPending, true},
+		{"debits_posted", aux.DebitsPosted, &a.DebitsPosted, true},
+		{"credits_pending", aux.CreditsPending, &a.CreditsPending, true},
+		{"credits_posted", aux.CreditsPosted, &a.CreditsPosted, true},
+	}
+	for _, f := range fields {
+		var value types.Uint128
+		var err error
+		if f.amount {
+			value, err = parseAmountField(f.name, f.raw, aux.Ledger, ledgers)
+		} else {
+			value, err = parseUint128Field(f.name, f.raw)
+		}
+		if err != nil {
+			return err
+		}
+		*f.dst = value
+	}
+
+	flags, err := parseFlagsField(aux.Flags, ParseAccountFlags)
+	if err != nil {
+		return err
+	}
+	a.Flags = flags
+
+	if aux.UserID != nil {
+		if aux.UserData128 != nil && userID != a.UserData128 {
+			return fmt.Errorf("field user_id: conflicts with user_data_128")
+		}
+		a.UserData128 = userID
+	}
+
+	a.UserData64 = aux.UserData64
+	a.UserData32 = aux.UserData32
 	a.Ledger = aux.Ledger
 	a.Code = aux.Code
-	a.Flags = aux.Flags
-	a.DebitsPending = types.ToUint128(aux.DebitsPending)
-	a.DebitsPosted = types.ToUint128(aux.DebitsPosted)
-	a.CreditsPending = types.ToUint128(aux.CreditsPending)
-	a.CreditsPosted = types.ToUint128(aux.CreditsPosted)
+	a.Timestamp = aux.Timestamp
 	return nil
 }
 
+// UnmarshalAccounts decodes a JSON array of accounts, reporting the index of
+// the first record that fails to decode. Balance fields given as decimals are
+// scaled using the ledger registry.
+func UnmarshalAccounts(data []byte, ledgers LedgerRegistry) ([]Account, error) {
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]Account, len(records))
+	for i, record := range records {
+		if err := accounts[i].unmarshalJSON(record, ledgers); err != nil {
+			return nil, fmt.Errorf("account at index %d: %w", i, err)
+		}
+	}
+	return accounts, nil
+}
+
+// NormalSide is the side of the ledger on which an account's balance grows.
+type NormalSide int
+
+const (
+	// CreditNormal accounts (liabilities, equity, income) grow with credits.
+	CreditNormal NormalSide = iota
+	// DebitNormal accounts (assets, expenses) grow with debits.
+	DebitNormal
+)
+
+func (s NormalSide) String() string {
+	if s == DebitNormal {
+		return "debit"
+	}
+	return "credit"
+}
+
+// NormalSideOf infers the normal side from account flags. An account whose
+// credits must not exceed its debits is debit-normal; anything else is
+// treated as credit-normal.
+func NormalSideOf(flags uint16) NormalSide {
+	if (types.Account{Flags: flags}).AccountFlags().CreditsMustNotExceedDebits {
+		return DebitNormal
+	}
+	return CreditNormal
+}
+
+// AccountBalances holds an account's counters together with its signed net
+// balances as seen from the account's normal side.
 type AccountBalances struct {
 	Account
+	Side NormalSide
+	// Balance is the net posted balance.
 	Balance *big.Int
+	// Pending is the net of pending credits and debits.
+	Pending *big.Int
+	// Available is the posted balance less pending amounts moving against
+	// the normal side. Pending amounts in favour of the account are not
+	// counted until they are posted.
+	Available *big.Int
+}
+
+// NewAccountBalances computes the balances of account, inferring the normal
+// side from its flags.
+func NewAccountBalances(account Account) *AccountBalances {
+	ab := &AccountBalances{Account: account, Side: NormalSideOf(account.Flags)}
+	ab.Compute()
+	return ab
+}
+
+// Compute recalculates Balance, Pending and Available from the counters.
+func (ab *AccountBalances) Compute() {
+	debitsPending := ab.DebitsPending.BigInt()
+	debitsPosted := ab.DebitsPosted.BigInt()
+	creditsPending := ab.CreditsPending.BigInt()
+	creditsPosted := ab.CreditsPosted.BigInt()
+
+	plusPosted, minusPosted := &creditsPosted, &debitsPosted
+	plusPending, minusPending := &creditsPending, &debitsPending
+	if ab.Side == DebitNormal {
+		plusPosted, minusPosted = minusPosted, plusPosted
+		plusPending, minusPending = minusPending, plusPending
+	}
+
+	ab.Balance = new(big.Int).Sub(plusPosted, minusPosted)
+	ab.Pending = new(big.Int).Sub(plusPending, minusPending)
+	ab.Available = new(big.Int).Sub(ab.Balance, minusPending)
 }
 
 func (a *Account) ToTigerBeetleAccount() types.Account {
 	return types.Account{
 		ID:             a.ID,
-		UserData128:    a.UserID, // Assuming UserID maps to UserData128
+		UserData128:    a.UserData128,
+		UserData64:     a.UserData64,
+		UserData32:     a.UserData32,
 		Ledger:         a.Ledger,
 		Code:           a.Code,
 		Flags:          a.Flags,
@@ -93,34 +236,27 @@ func (a *Account) ToTigerBeetleAccount() types.Account {
 		DebitsPosted:   a.DebitsPosted,
 		CreditsPending: a.CreditsPending,
 		CreditsPosted:  a.CreditsPosted,
+		Timestamp:      a.Timestamp,
 	}
 }
 
 func (a *Account) FromTigerBeetleAccount(tba types.Account) {
-	a.ID = tba.ID
-	a.UserID = tba.UserData128 // Assuming UserData128 maps to UserID
-	a.Ledger = tba.Ledger
-	a.Code = tba.Code
-	a.Flags = tba.Flags
-	a.DebitsPending = tba.DebitsPending
-	a.DebitsPosted = tba.DebitsPosted
-	a.CreditsPending = tba.CreditsPending
-	a.CreditsPosted = tba.CreditsPosted
+	*a = *FromTigerBeetleAccount(tba)
 }
 
+// FromTigerBeetleAccountBalance loads a balance snapshot and recomputes the
+// balances using the current Side.
 func (ab *AccountBalances) FromTigerBeetleAccountBalance(tba types.AccountBalance) {
 	ab.DebitsPending = tba.DebitsPending
 	ab.DebitsPosted = tba.DebitsPosted
 	ab.CreditsPending = tba.CreditsPending
 	ab.CreditsPosted = tba.CreditsPosted
-	// Note: We're not setting the Balance field here as it's not directly available in types.AccountBalance
+	ab.Timestamp = tba.Timestamp
+	ab.Compute()
 }
 
 func (ab *AccountBalances) ToTigerBeetleAccountBalances() (types.AccountBalance, error) {
-	maxUint128 := types.ToUint128(^uint64(0))
-	maxBigInt := maxUint128.BigInt()
-
-	if ab.Balance.Cmp(&maxBigInt) > 0 {
+	if ab.Balance != nil && new(big.Int).Abs(ab.Balance).Cmp(maxUint128) > 0 {
 		return types.AccountBalance{}, errors.New("balance exceeds maximum uint128 value")
 	}
 
@@ -129,20 +265,16 @@ func (ab *AccountBalances) ToTigerBeetleAccountBalances() (types.AccountBalance,
 		DebitsPosted:   ab.DebitsPosted,
 		CreditsPending: ab.CreditsPending,
 		CreditsPosted:  ab.CreditsPosted,
-		// Note: There's no direct field for Balance in types.AccountBalance
+		Timestamp:      ab.Timestamp,
 	}, nil
 }
 
-// Helper function to convert types.Uint128 to uint64
-func uint64FromUint128(u types.Uint128) uint64 {
-	bigInt := u.BigInt()
-	return bigInt.Uint64()
-}
-
 func FromTigerBeetleAccount(tba types.Account) *Account {
 	return &Account{
 		ID:             tba.ID,
-		UserID:         tba.UserData128, // Assuming UserData128 maps to UserID
+		UserData128:    tba.UserData128,
+		UserData64:     tba.UserData64,
+		UserData32:     tba.UserData32,
 		Ledger:         tba.Ledger,
 		Code:           tba.Code,
 		Flags:          tba.Flags,
@@ -150,5 +282,6 @@ func FromTigerBeetleAccount(tba types.Account) *Account {
 		DebitsPosted:   tba.DebitsPosted,
 		CreditsPending: tba.CreditsPending,
 		CreditsPosted:  tba.CreditsPosted,
+		Timestamp:      tba.Timestamp,
 	}
 }
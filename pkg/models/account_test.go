@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func TestParseUint128(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"Decimal", "42", "42", false},
+		{"Above uint64", "18446744073709551616", "18446744073709551616", false},
+		{"Max uint128", "340282366920938463463374607431768211455", "340282366920938463463374607431768211455", false},
+		{"Hex", "0xff", "255", false},
+		{"UUID", "00000000-0000-0000-0000-000000000100", "256", false},
+		{"Overflow", "340282366920938463463374607431768211456", "", true},
+		{"Negative", "-1", "", true},
+		{"Fraction", "1.5", "", true},
+		{"Hex too long", "0x1" + "00000000000000000000000000000000", "", true},
+		{"Empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ParseUint128(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, FormatUint128(value))
+		})
+	}
+}
+
+func TestAccountJSONRoundTrip(t *testing.T) {
+	id, err := ParseUint128("0x0190b5d6c2f04f6b8e4a1b2c3d4e5f60")
+	require.NoError(t, err)
+
+	account := Account{ID: id, Ledger: 700, Code: 10}
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"2080606874709579209382432304490504032"`)
+
+	var decoded Account
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, account, decoded)
+}
+
+func TestUnmarshalAccountsReportsFieldAndIndex(t *testing.T) {
+	data := []byte(`[
+		{"id": 1, "ledger": 700, "code": 10},
+		{"id": "2", "ledger": 700, "code": 10},
+		{"id": 3, "credits_posted": "0xzz", "ledger": 700, "code": 10}
+	]`)
+
+	_, err := UnmarshalAccounts(data, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index 2")
+	assert.Contains(t, err.Error(), "credits_posted")
+}
+
+func TestAccountUserIDAlias(t *testing.T) {
+	var account Account
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 1, "user_id": "42", "ledger": 700, "code": 10}`), &account))
+	assert.Equal(t, "42", FormatUint128(account.UserData128))
+
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"user_data_128":"42"`)
+	assert.NotContains(t, string(data), "user_id")
+
+	err = json.Unmarshal([]byte(`{"id": 1, "user_id": "42", "user_data_128": "43"}`), &account)
+	assert.Error(t, err)
+}
+
+func TestAccountTigerBeetleConversionIsLossless(t *testing.T) {
+	tba := types.Account{
+		ID:             types.ToUint128(1),
+		DebitsPending:  types.ToUint128(2),
+		DebitsPosted:   types.ToUint128(3),
+		CreditsPending: types.ToUint128(4),
+		CreditsPosted:  types.ToUint128(5),
+		UserData128:    types.ToUint128(6),
+		UserData64:     7,
+		UserData32:     8,
+		Ledger:         700,
+		Code:           10,
+		Flags:          1,
+		Timestamp:      1700000000000000000,
+	}
+
+	account := FromTigerBeetleAccount(tba)
+	assert.Equal(t, tba, account.ToTigerBeetleAccount())
+
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	var decoded Account
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *account, decoded)
+}
+
+func TestAccountBalancesNormalSide(t *testing.T) {
+	account := Account{
+		DebitsPending:  types.ToUint128(5),
+		DebitsPosted:   types.ToUint128(30),
+		CreditsPending: types.ToUint128(20),
+		CreditsPosted:  types.ToUint128(100),
+	}
+
+	credit := NewAccountBalances(account)
+	assert.Equal(t, CreditNormal, credit.Side)
+	assert.Equal(t, "70", credit.Balance.String())
+	assert.Equal(t, "15", credit.Pending.String())
+	assert.Equal(t, "65", credit.Available.String())
+
+	account.Flags = types.AccountFlags{CreditsMustNotExceedDebits: true}.ToUint16()
+	debit := NewAccountBalances(account)
+	assert.Equal(t, DebitNormal, debit.Side)
+	assert.Equal(t, "-70", debit.Balance.String())
+	assert.Equal(t, "-15", debit.Pending.String())
+	assert.Equal(t, "-90", debit.Available.String())
+
+	largest, err := ParseUint128("340282366920938463463374607431768211455")
+	require.NoError(t, err)
+	debit.DebitsPosted = largest
+	debit.Compute()
+	_, err = debit.ToTigerBeetleAccountBalances()
+	assert.NoError(t, err)
+}
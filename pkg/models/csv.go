@@ -0,0 +1,338 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CSVMapping describes how the columns of a CSV file map to account or
+// transfer fields. Columns maps a field name to the header of the column
+// that feeds it, optionally scaled by a constant:
+//
+//	columns:
+//	  id: account_number
+//	  amount: amount_usd * 100
+//	defaults:
+//	  ledger: 700
+//	  code: 10
+//	  flags: history
+//
+// Defaults gives constant values for fields without a column, or for rows
+// where the column is empty.
+type CSVMapping struct {
+	Delimiter string            `mapstructure:"delimiter"`
+	Columns   map[string]string `mapstructure:"columns"`
+	Defaults  map[string]string `mapstructure:"defaults"`
+}
+
+var (
+	accountFields = []string{
+		"id", "user_data_128", "user_id", "user_data_64", "user_data_32", "ledger", "code", "flags",
+		"debits_pending", "debits_posted", "credits_pending", "credits_posted", "timestamp",
+	}
+	transferFields = []string{
+		"id", "debit_account_id", "credit_account_id", "amount", "pending_id", "user_data_128",
+		"user_data_64", "user_data_32", "timeout", "ledger", "code", "flags", "timestamp",
+	}
+)
+
+// columnExpr matches a column scaled by a constant, such as "amount * 100".
+var columnExpr = regexp.MustCompile(`^(.+?)\s*([*/])\s*([0-9]+(?:\.[0-9]+)?)$`)
+
+// csvColumn is a resolved Columns entry.
+type csvColumn struct {
+	field  string
+	index  int
+	factor *big.Rat
+}
+
+// CSVRecordError is a CSV row that could not be converted to a record. The
+// stream can continue past it.
+type CSVRecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *CSVRecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *CSVRecordError) Unwrap() error {
+	return e.Err
+}
+
+// CSVStream reads accounts or transfers from CSV with a header row, one
+// record per row, using a CSVMapping.
+type CSVStream struct {
+	mapping CSVMapping
+	counter *countingReader
+	reader  *csv.Reader
+	columns []csvColumn
+	checked bool
+	index   int
+	// line is the last input line read. csv.Reader only reports positions
+	// with errors, so lines are counted from the rows themselves.
+	line int
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewCSVStream returns a stream over CSV input r mapped with mapping.
+func NewCSVStream(r io.Reader, mapping CSVMapping) (*CSVStream, error) {
+	counter := &countingReader{r: r}
+	reader := csv.NewReader(counter)
+	if mapping.Delimiter != "" {
+		runes := []rune(mapping.Delimiter)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("invalid mapping: delimiter %q must be a single character", mapping.Delimiter)
+		}
+		reader.Comma = runes[0]
+	}
+	return &CSVStream{mapping: mapping, counter: counter, reader: reader}, nil
+}
+
+// Index returns the number of rows read so far, not counting the header.
+func (s *CSVStream) Index() int {
+	return s.index
+}
+
+// Offset returns how many bytes of the input have been consumed. The CSV
+// reader buffers ahead, so this may be past the last row returned.
+func (s *CSVStream) Offset() int64 {
+	return s.counter.n
+}
+
+// NextAccount converts the next row to an account. It returns io.EOF at the
+// end of the input, and a *CSVRecordError for a row with invalid values.
+func (s *CSVStream) NextAccount(account *Account, ledgers LedgerRegistry) error {
+	if err := s.check(accountFields); err != nil {
+		return err
+	}
+	raw, line, err := s.next()
+	if err != nil {
+		return err
+	}
+	*account = Account{}
+	if err := account.unmarshalJSON(raw, ledgers); err != nil {
+		return &CSVRecordError{Line: line, Err: fmt.Errorf("account: %w", err)}
+	}
+	return nil
+}
+
+// NextTransfer converts the next row to a transfer. It returns io.EOF at the
+// end of the input, and a *CSVRecordError for a row with invalid values.
+func (s *CSVStream) NextTransfer(transfer *Transfer, ledgers LedgerRegistry) error {
+	if err := s.check(transferFields); err != nil {
+		return err
+	}
+	raw, line, err := s.next()
+	if err != nil {
+		return err
+	}
+	*transfer = Transfer{}
+	if err := transfer.unmarshalJSON(raw, ledgers); err != nil {
+		return &CSVRecordError{Line: line, Err: fmt.Errorf("transfer: %w", err)}
+	}
+	return nil
+}
+
+// Skip reads past the next n rows without converting them, for resuming a
+// migration from a row count. It returns io.EOF if the input has fewer rows.
+func (s *CSVStream) Skip(n int) error {
+	if s.columns == nil {
+		if err := s.readHeader(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < n; i++ {
+		row, err := s.reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+			s.line = parseErr.StartLine - 1
+		} else if err != nil {
+			return err
+		}
+		s.advance(row)
+		s.index++
+	}
+	return nil
+}
+
+// check rejects mapping entries for fields the record type does not have.
+func (s *CSVStream) check(fields []string) error {
+	if s.checked {
+		return nil
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f] = true
+	}
+	for _, section := range []map[string]string{s.mapping.Columns, s.mapping.Defaults} {
+		for field := range section {
+			if !known[field] {
+				return fmt.Errorf("invalid mapping: unknown field %q (valid fields: %s)", field, strings.Join(fields, ", "))
+			}
+		}
+	}
+	s.checked = true
+	return nil
+}
+
+// advance moves past row and returns the line it starts on. A row spans one
+// line plus any line breaks inside its quoted fields.
+func (s *CSVStream) advance(row []string) int {
+	line := s.line + 1
+	s.line = line
+	for _, field := range row {
+		s.line += strings.Count(field, "\n")
+	}
+	return line
+}
+
+// readHeader resolves the mapped columns against the header row.
+func (s *CSVStream) readHeader() error {
+	header, err := s.reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("CSV input has no header row")
+	}
+	if err != nil {
+		return err
+	}
+	s.advance(header)
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.TrimSpace(name)] = i
+	}
+
+	fields := make([]string, 0, len(s.mapping.Columns))
+	for field := range s.mapping.Columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	s.columns = make([]csvColumn, 0, len(fields))
+	for _, field := range fields {
+		expr := strings.TrimSpace(s.mapping.Columns[field])
+		column := csvColumn{field: field}
+
+		name := expr
+		if m := columnExpr.FindStringSubmatch(expr); m != nil {
+			name = m[1]
+			factor, ok := new(big.Rat).SetString(m[3])
+			if !ok || factor.Sign() == 0 {
+				return fmt.Errorf("invalid mapping for %s: bad constant in %q", field, expr)
+			}
+			if m[2] == "/" {
+				factor.Inv(factor)
+			}
+			column.factor = factor
+		}
+
+		index, ok := positions[name]
+		if !ok {
+			return fmt.Errorf("invalid mapping for %s: no column %q in header", field, name)
+		}
+		column.index = index
+		s.columns = append(s.columns, column)
+	}
+	return nil
+}
+
+// next reads the next row and converts it to a JSON object, so that it is
+// parsed and validated exactly like a JSON record.
+func (s *CSVStream) next() (json.RawMessage, int, error) {
+	if s.columns == nil {
+		if err := s.readHeader(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	row, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+		s.line = parseErr.StartLine - 1
+		s.advance(row)
+		s.index++
+		return nil, parseErr.StartLine, &CSVRecordError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line := s.advance(row)
+	s.index++
+
+	values := make(map[string]string, len(s.mapping.Defaults)+len(s.columns))
+	for field, value := range s.mapping.Defaults {
+		values[field] = value
+	}
+	for _, column := range s.columns {
+		value := strings.TrimSpace(row[column.index])
+		if value == "" {
+			continue
+		}
+		if column.factor != nil {
+			scaled, err := scaleValue(value, column.factor)
+			if err != nil {
+				return nil, line, &CSVRecordError{Line: line, Err: fmt.Errorf("field %s: %w", column.field, err)}
+			}
+			value = scaled
+		}
+		values[column.field] = value
+	}
+
+	object := make(map[string]json.RawMessage, len(values))
+	for field, value := range values {
+		object[field] = csvValue(value)
+	}
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return nil, line, err
+	}
+	return raw, line, nil
+}
+
+// scaleValue multiplies a decimal by factor, which must give a whole number.
+func scaleValue(value string, factor *big.Rat) (string, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return "", fmt.Errorf("%q is not a number", value)
+	}
+	r.Mul(r, factor)
+	if !r.IsInt() {
+		return "", fmt.Errorf("%q does not scale to a whole number", value)
+	}
+	return r.Num().String(), nil
+}
+
+// csvValue encodes a cell for the JSON decoder: unsigned integers as JSON
+// numbers, so they can feed integer fields, and anything else as a string.
+func csvValue(value string) json.RawMessage {
+	if strings.Trim(value, "0123456789") == "" {
+		if value = strings.TrimLeft(value, "0"); value == "" {
+			value = "0"
+		}
+		return json.RawMessage(value)
+	}
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
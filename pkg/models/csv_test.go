@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVStreamTransfers(t *testing.T) {
+	mapping := CSVMapping{
+		Columns: map[string]string{
+			"id":                "ref",
+			"debit_account_id":  "from",
+			"credit_account_id": "to",
+			"amount":            "amount_usd * 100",
+			"code":              "type",
+		},
+		Defaults: map[string]string{"ledger": "700", "code": "10", "flags": "linked"},
+	}
+	input := `ref,from,to,amount_usd,type
+1,1001,1002,12.34,
+2,1001,1002,0.5,20
+3,1001,1002,1.005,
+4,1001,1002
+`
+	stream, err := NewCSVStream(strings.NewReader(input), mapping)
+	require.NoError(t, err)
+
+	var transfer Transfer
+	require.NoError(t, stream.NextTransfer(&transfer, nil))
+	assert.Equal(t, "1234", FormatUint128(transfer.Amount))
+	assert.Equal(t, uint32(700), transfer.Ledger)
+	assert.Equal(t, uint16(10), transfer.Code)
+	assert.Equal(t, []string{"linked"}, TransferFlagNames(transfer.Flags))
+
+	// A non-empty column overrides the default.
+	require.NoError(t, stream.NextTransfer(&transfer, nil))
+	assert.Equal(t, "50", FormatUint128(transfer.Amount))
+	assert.Equal(t, uint16(20), transfer.Code)
+
+	var rowErr *CSVRecordError
+	err = stream.NextTransfer(&transfer, nil)
+	require.True(t, errors.As(err, &rowErr))
+	assert.Equal(t, 4, rowErr.Line)
+	assert.Contains(t, err.Error(), "field amount")
+
+	err = stream.NextTransfer(&transfer, nil)
+	require.True(t, errors.As(err, &rowErr))
+	assert.Equal(t, 5, rowErr.Line)
+
+	assert.Equal(t, io.EOF, stream.NextTransfer(&transfer, nil))
+	assert.Equal(t, 4, stream.Index())
+}
+
+func TestCSVStreamAccounts(t *testing.T) {
+	mapping := CSVMapping{
+		Delimiter: ";",
+		Columns:   map[string]string{"id": "account", "user_data_64": "customer"},
+		Defaults:  map[string]string{"ledger": "700", "code": "10"},
+	}
+	stream, err := NewCSVStream(strings.NewReader("account;customer\n0042;7\nabc;8\n"), mapping)
+	require.NoError(t, err)
+
+	var account Account
+	require.NoError(t, stream.NextAccount(&account, nil))
+	assert.Equal(t, "42", FormatUint128(account.ID))
+	assert.Equal(t, uint64(7), account.UserData64)
+
+	err = stream.NextAccount(&account, nil)
+	assert.ErrorContains(t, err, "line 3: account: field id")
+}
+
+func TestCSVStreamSkip(t *testing.T) {
+	mapping := CSVMapping{Columns: map[string]string{"id": "id"}}
+	stream, err := NewCSVStream(strings.NewReader("id\n1\n2,extra\n3\n"), mapping)
+	require.NoError(t, err)
+
+	// Rows with the wrong number of fields are skipped like any other.
+	require.NoError(t, stream.Skip(2))
+	assert.Equal(t, 2, stream.Index())
+
+	var account Account
+	require.NoError(t, stream.NextAccount(&account, nil))
+	assert.Equal(t, "3", FormatUint128(account.ID))
+	assert.Equal(t, io.EOF, stream.Skip(1))
+}
+
+func TestCSVMappingErrors(t *testing.T) {
+	stream, err := NewCSVStream(strings.NewReader("a,b\n1,2\n"), CSVMapping{Columns: map[string]string{"amount": "a"}})
+	require.NoError(t, err)
+	var account Account
+	assert.ErrorContains(t, stream.NextAccount(&account, nil), `unknown field "amount"`)
+
+	stream, err = NewCSVStream(strings.NewReader("a,b\n1,2\n"), CSVMapping{Columns: map[string]string{"id": "c"}})
+	require.NoError(t, err)
+	assert.ErrorContains(t, stream.NextAccount(&account, nil), `no column "c" in header`)
+
+	_, err = NewCSVStream(strings.NewReader(""), CSVMapping{Delimiter: "||"})
+	assert.Error(t, err)
+}
+
+func TestCSVStreamLinesAfterMultilineField(t *testing.T) {
+	mapping := CSVMapping{Columns: map[string]string{"id": "id", "ledger": "ledger", "code": "code"}}
+	input := "id,ledger,code,note\n1,700,10,\"two\nlines\"\nx,700,10,\n"
+	stream, err := NewCSVStream(strings.NewReader(input), mapping)
+	require.NoError(t, err)
+
+	var account Account
+	require.NoError(t, stream.NextAccount(&account, nil))
+
+	var rowErr *CSVRecordError
+	err = stream.NextAccount(&account, nil)
+	require.True(t, errors.As(err, &rowErr))
+	assert.Equal(t, 4, rowErr.Line)
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+type flagName struct {
+	name string
+	bit  uint16
+}
+
+var accountFlagNames = []flagName{
+	{"linked", types.AccountFlags{Linked: true}.ToUint16()},
+	{"debits_must_not_exceed_credits", types.AccountFlags{DebitsMustNotExceedCredits: true}.ToUint16()},
+	{"credits_must_not_exceed_debits", types.AccountFlags{CreditsMustNotExceedDebits: true}.ToUint16()},
+	{"history", types.AccountFlags{History: true}.ToUint16()},
+}
+
+var transferFlagNames = []flagName{
+	{"linked", types.TransferFlags{Linked: true}.ToUint16()},
+	{"pending", types.TransferFlags{Pending: true}.ToUint16()},
+	{"post_pending_transfer", types.TransferFlags{PostPendingTransfer: true}.ToUint16()},
+	{"void_pending_transfer", types.TransferFlags{VoidPendingTransfer: true}.ToUint16()},
+	{"balancing_debit", types.TransferFlags{BalancingDebit: true}.ToUint16()},
+	{"balancing_credit", types.TransferFlags{BalancingCredit: true}.ToUint16()},
+}
+
+// ParseAccountFlags parses a comma-separated list of account flag names, or a
+// raw integer, and validates the resulting combination.
+func ParseAccountFlags(s string) (uint16, error) {
+	flags, err := parseFlags(s, accountFlagNames)
+	if err != nil {
+		return 0, err
+	}
+	return flags, ValidateAccountFlags(flags)
+}
+
+// ParseTransferFlags parses a comma-separated list of transfer flag names, or
+// a raw integer, and validates the resulting combination.
+func ParseTransferFlags(s string) (uint16, error) {
+	flags, err := parseFlags(s, transferFlagNames)
+	if err != nil {
+		return 0, err
+	}
+	return flags, ValidateTransferFlags(flags)
+}
+
+// AccountFlagNames returns the names of the account flags set in flags.
+func AccountFlagNames(flags uint16) []string {
+	return flagNames(flags, accountFlagNames)
+}
+
+// TransferFlagNames returns the names of the transfer flags set in flags.
+func TransferFlagNames(flags uint16) []string {
+	return flagNames(flags, transferFlagNames)
+}
+
+// ValidateAccountFlags rejects unknown bits and combinations the cluster
+// would refuse.
+func ValidateAccountFlags(flags uint16) error {
+	if err := checkKnownBits(flags, accountFlagNames); err != nil {
+		return err
+	}
+	f := types.Account{Flags: flags}.AccountFlags()
+	if f.DebitsMustNotExceedCredits && f.CreditsMustNotExceedDebits {
+		return fmt.Errorf("flags debits_must_not_exceed_credits and credits_must_not_exceed_debits are mutually exclusive")
+	}
+	return nil
+}
+
+// ValidateTransferFlags rejects unknown bits and combinations the cluster
+// would refuse.
+func ValidateTransferFlags(flags uint16) error {
+	if err := checkKnownBits(flags, transferFlagNames); err != nil {
+		return err
+	}
+	f := types.Transfer{Flags: flags}.TransferFlags()
+	phases := 0
+	for _, set := range []bool{f.Pending, f.PostPendingTransfer, f.VoidPendingTransfer} {
+		if set {
+			phases++
+		}
+	}
+	if phases > 1 {
+		return fmt.Errorf("flags pending, post_pending_transfer and void_pending_transfer are mutually exclusive")
+	}
+	if (f.BalancingDebit || f.BalancingCredit) && (f.PostPendingTransfer || f.VoidPendingTransfer) {
+		return fmt.Errorf("balancing flags cannot be combined with post_pending_transfer or void_pending_transfer")
+	}
+	return nil
+}
+
+func parseFlags(s string, known []flagName) (uint16, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 16); err == nil {
+		return uint16(n), nil
+	}
+
+	var flags uint16
+	for _, part := range strings.Split(s, ",") {
+		bit, err := lookupFlag(part, known)
+		if err != nil {
+			return 0, err
+		}
+		flags |= bit
+	}
+	return flags, nil
+}
+
+func lookupFlag(name string, known []flagName) (uint16, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))
+	for _, f := range known {
+		if f.name == name {
+			return f.bit, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown flag %q", name)
+}
+
+func flagNames(flags uint16, known []flagName) []string {
+	names := []string{}
+	for _, f := range known {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func checkKnownBits(flags uint16, known []flagName) error {
+	var mask uint16
+	for _, f := range known {
+		mask |= f.bit
+	}
+	if flags&^mask != 0 {
+		return fmt.Errorf("unknown flag bits 0x%x", flags&^mask)
+	}
+	return nil
+}
+
+// parseFlagsField decodes a JSON flags value given as an integer, a
+// comma-separated string or an array of flag names.
+func parseFlagsField(raw json.RawMessage, parse func(string) (uint16, error)) (uint16, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return parse("")
+	}
+
+	var s string
+	switch raw[0] {
+	case '[':
+		var names []string
+		if err := json.Unmarshal(raw, &names); err != nil {
+			return 0, fmt.Errorf("field flags: %w", err)
+		}
+		s = strings.Join(names, ",")
+	case '"':
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, fmt.Errorf("field flags: %w", err)
+		}
+	default:
+		s = string(raw)
+	}
+
+	flags, err := parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("field flags: %w", &FlagsError{Err: err})
+	}
+	return flags, nil
+}
+
+// FlagsError is a flags field that names an unknown flag or combines flags
+// the cluster would refuse.
+type FlagsError struct {
+	Err error
+}
+
+func (e *FlagsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FlagsError) Unwrap() error {
+	return e.Err
+}
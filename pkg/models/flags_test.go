@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccountFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected uint16
+		wantErr  bool
+	}{
+		{"Empty", "", 0, false},
+		{"Integer", "2", 2, false},
+		{"Names", "linked,history", 9, false},
+		{"Hyphenated", "debits-must-not-exceed-credits", 2, false},
+		{"Unknown name", "pending", 0, true},
+		{"Unknown bit", "16", 0, true},
+		{"Both must-not-exceed", "debits_must_not_exceed_credits,credits_must_not_exceed_debits", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := ParseAccountFlags(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, flags)
+		})
+	}
+}
+
+func TestParseTransferFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected uint16
+		wantErr  bool
+	}{
+		{"Linked pending", "linked,pending", 3, false},
+		{"Balancing", "balancing_debit,balancing_credit", 48, false},
+		{"Post and void", "post_pending_transfer,void_pending_transfer", 0, true},
+		{"Pending and post", "pending,post_pending_transfer", 0, true},
+		{"Balancing and void", "balancing_debit,void_pending_transfer", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := ParseTransferFlags(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, flags)
+		})
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	assert.Equal(t, []string{"linked", "history"}, AccountFlagNames(9))
+	assert.Equal(t, []string{"pending"}, TransferFlagNames(2))
+	assert.Equal(t, []string{}, AccountFlagNames(0))
+}
+
+func TestFlagsJSONForms(t *testing.T) {
+	var account Account
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 1, "flags": ["linked", "history"]}`), &account))
+	assert.Equal(t, uint16(9), account.Flags)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 1, "flags": "history"}`), &account))
+	assert.Equal(t, uint16(8), account.Flags)
+
+	var transfer Transfer
+	err := json.Unmarshal([]byte(`{"id": 1, "flags": ["post_pending_transfer", "void_pending_transfer"]}`), &transfer)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "field flags")
+
+	var flagsErr *FlagsError
+	assert.True(t, errors.As(err, &flagsErr))
+	err = json.Unmarshal([]byte(`{"id": 1, "flags": ["linked"`), &transfer)
+	assert.False(t, errors.As(err, &flagsErr))
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// LedgerInfo describes the asset held on a ledger. Amounts on the ledger are
+// integers in minor units; Scale is the number of decimal places between the
+// minor and major unit (2 for cents). EquityAccount is the account opening
+// balances on the ledger are posted against.
+type LedgerInfo struct {
+	Currency      string `mapstructure:"currency"`
+	Scale         uint8  `mapstructure:"scale"`
+	EquityAccount string `mapstructure:"equity_account"`
+}
+
+// LedgerRegistry maps ledger IDs to the asset they hold.
+type LedgerRegistry map[uint32]LedgerInfo
+
+// ParseAmount parses an amount for the given ledger. Plain integers are taken
+// as minor units. Decimals such as "12.34" are major units and are scaled by
+// the ledger's asset scale; they must convert exactly, without rounding.
+func (r LedgerRegistry) ParseAmount(s string, ledger uint32) (types.Uint128, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ".") {
+		return ParseUint128(s)
+	}
+
+	info, ok := r[ledger]
+	if !ok {
+		return types.Uint128{}, fmt.Errorf("decimal amount %q needs an asset scale, but ledger %d has none configured", s, ledger)
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	whole, frac := parts[0], parts[1]
+	if !isDecimal(whole) || !isDecimal(frac) || whole+frac == "" {
+		return types.Uint128{}, fmt.Errorf("invalid amount %q: expected decimal digits around the decimal point", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > int(info.Scale) {
+		if strings.TrimRight(frac[info.Scale:], "0") != "" {
+			return types.Uint128{}, fmt.Errorf("amount %q has more than %d decimal places for ledger %d", s, info.Scale, ledger)
+		}
+		frac = frac[:info.Scale]
+	}
+	frac += strings.Repeat("0", int(info.Scale)-len(frac))
+
+	amount, err := ParseUint128(whole + frac)
+	if err != nil {
+		return types.Uint128{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	return amount, nil
+}
+
+// isDecimal reports whether s holds only decimal digits. The empty string
+// counts as decimal.
+func isDecimal(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatAmount formats a signed amount in minor units as a decimal in major
+// units followed by the ledger's currency. Ledgers without a registry entry
+// are formatted as plain integers.
+func (r LedgerRegistry) FormatAmount(amount *big.Int, ledger uint32) string {
+	info, ok := r[ledger]
+	if !ok {
+		return amount.String()
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	if info.Scale > 0 {
+		if len(digits) <= int(info.Scale) {
+			digits = strings.Repeat("0", int(info.Scale)-len(digits)+1) + digits
+		}
+		point := len(digits) - int(info.Scale)
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if amount.Sign() < 0 {
+		digits = "-" + digits
+	}
+	if info.Currency == "" {
+		return digits
+	}
+	return digits + " " + info.Currency
+}
+
+// FormatUint128Amount is FormatAmount for an unsigned 128-bit amount.
+func (r LedgerRegistry) FormatUint128Amount(amount types.Uint128, ledger uint32) string {
+	value := amount.BigInt()
+	return r.FormatAmount(&value, ledger)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerRegistryParseAmount(t *testing.T) {
+	ledgers := LedgerRegistry{
+		700: {Currency: "USD", Scale: 2},
+		800: {Currency: "JPY", Scale: 0},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		ledger   uint32
+		expected string
+		wantErr  bool
+	}{
+		{"Minor units", "1234", 700, "1234", false},
+		{"Decimal", "12.34", 700, "1234", false},
+		{"Short fraction", "12.3", 700, "1230", false},
+		{"Leading point", ".05", 700, "5", false},
+		{"Trailing zeros", "12.3400", 700, "1234", false},
+		{"Too precise", "12.345", 700, "", true},
+		{"Zero scale", "12.", 800, "12", false},
+		{"Zero scale fraction", "12.5", 800, "", true},
+		{"Unknown ledger", "12.34", 900, "", true},
+		{"Negative", "-12.34", 700, "", true},
+		{"Hex with point", "0x1.5", 700, "", true},
+		{"Lone point", ".", 700, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ledgers.ParseAmount(tt.input, tt.ledger)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, FormatUint128(amount))
+		})
+	}
+}
+
+func TestLedgerRegistryFormatAmount(t *testing.T) {
+	ledgers := LedgerRegistry{700: {Currency: "USD", Scale: 2}}
+
+	assert.Equal(t, "12.34 USD", ledgers.FormatAmount(big.NewInt(1234), 700))
+	assert.Equal(t, "0.05 USD", ledgers.FormatAmount(big.NewInt(5), 700))
+	assert.Equal(t, "-1.00 USD", ledgers.FormatAmount(big.NewInt(-100), 700))
+	assert.Equal(t, "1234", ledgers.FormatAmount(big.NewInt(1234), 800))
+}
+
+func TestUnmarshalTransfersScalesDecimalAmounts(t *testing.T) {
+	ledgers := LedgerRegistry{700: {Currency: "USD", Scale: 2}}
+	data := []byte(`[{"id": 1, "debit_account_id": 1, "credit_account_id": 2, "amount": "12.34", "ledger": 700, "code": 10}]`)
+
+	transfers, err := UnmarshalTransfers(data, ledgers)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", FormatUint128(transfers[0].Amount))
+
+	_, err = UnmarshalTransfers(data, nil)
+	assert.Error(t, err)
+}
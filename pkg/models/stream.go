@@ -0,0 +1,220 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// RecordStream reads JSON records one at a time from either a JSON array or
+// newline-delimited JSON, holding at most one record in memory.
+type RecordStream struct {
+	r       *bufio.Reader
+	dec     *json.Decoder
+	skipped int64
+	array   bool
+	index   int
+}
+
+// NewRecordStream returns a stream over r. The format is detected from the
+// first non-whitespace byte: '[' selects a JSON array, anything else
+// newline-delimited JSON.
+func NewRecordStream(r io.Reader) *RecordStream {
+	return &RecordStream{r: bufio.NewReader(r)}
+}
+
+// ResumeRecordStream returns a stream over r that continues after a record
+// previously read from the same input. offset and index are the values Offset
+// and Index reported just after that record; later calls to them continue
+// from those values.
+func ResumeRecordStream(r io.ReadSeeker, offset int64, index int) (*RecordStream, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	probe := NewRecordStream(r)
+	if err := probe.start(); err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	s := &RecordStream{r: bufio.NewReader(r), skipped: offset, array: probe.array, index: index}
+	if !s.array {
+		s.dec = json.NewDecoder(s.r)
+		return s, nil
+	}
+
+	// Drop the separator after the last record read and reopen the array,
+	// so the decoder sees the remaining records as a complete array.
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		s.skipped++
+		if c == ',' {
+			break
+		}
+		if c == ']' {
+			if err := s.r.UnreadByte(); err != nil {
+				return nil, err
+			}
+			s.skipped--
+			break
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return nil, fmt.Errorf("error resuming input: unexpected %q at offset %d", c, s.skipped-1)
+		}
+	}
+	s.skipped--
+	s.dec = json.NewDecoder(io.MultiReader(strings.NewReader("["), s.r))
+	if _, err := s.dec.Token(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Next returns the next raw record, or io.EOF when the input is exhausted.
+func (s *RecordStream) Next() (json.RawMessage, error) {
+	if s.dec == nil {
+		if err := s.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.array && !s.dec.More() {
+		if _, err := s.dec.Token(); err != nil {
+			return nil, fmt.Errorf("error reading end of array: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		if err == io.EOF && !s.array {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("record at index %d: %w", s.index, err)
+	}
+	s.index++
+	return raw, nil
+}
+
+func (s *RecordStream) start() error {
+	// Skip leading whitespace so the first byte can be inspected without the
+	// decoder consuming it.
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			if err := s.r.UnreadByte(); err != nil {
+				return err
+			}
+			s.array = c == '['
+			break
+		}
+		s.skipped++
+	}
+
+	s.dec = json.NewDecoder(s.r)
+	if s.array {
+		if _, err := s.dec.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Index returns the number of records read so far.
+func (s *RecordStream) Index() int {
+	return s.index
+}
+
+// Offset returns the byte offset in the input just past the last record read.
+func (s *RecordStream) Offset() int64 {
+	if s.dec == nil {
+		return s.skipped
+	}
+	return s.skipped + s.dec.InputOffset()
+}
+
+// NextAccount decodes the next record as an account, scaling decimal
+// balances with ledgers. It returns io.EOF at the end of the input.
+func (s *RecordStream) NextAccount(account *Account, ledgers LedgerRegistry) error {
+	raw, err := s.Next()
+	if err != nil {
+		return err
+	}
+	*account = Account{}
+	if err := account.unmarshalJSON(raw, ledgers); err != nil {
+		return fmt.Errorf("account at index %d: %w", s.index-1, err)
+	}
+	return nil
+}
+
+// NextTransfer decodes the next record as a transfer, scaling decimal
+// amounts with ledgers. It returns io.EOF at the end of the input.
+func (s *RecordStream) NextTransfer(transfer *Transfer, ledgers LedgerRegistry) error {
+	raw, err := s.Next()
+	if err != nil {
+		return err
+	}
+	*transfer = Transfer{}
+	if err := transfer.unmarshalJSON(raw, ledgers); err != nil {
+		return fmt.Errorf("transfer at index %d: %w", s.index-1, err)
+	}
+	return nil
+}
+
+// NextTransferChain decodes the next record as a chain of transfers that
+// must succeed or fail together. A record is either a single transfer or an
+// object of the form {"chain": [transfer, ...]}. The linked flag is set on
+// every member but the last, so the chain is closed. It returns io.EOF at
+// the end of the input.
+func (s *RecordStream) NextTransferChain(ledgers LedgerRegistry) ([]Transfer, error) {
+	raw, err := s.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	var group struct {
+		Chain json.RawMessage `json:"chain"`
+	}
+	if err := json.Unmarshal(raw, &group); err != nil {
+		return nil, fmt.Errorf("chain at index %d: %w", s.index-1, err)
+	}
+
+	var chain []Transfer
+	if group.Chain == nil {
+		var transfer Transfer
+		if err := transfer.unmarshalJSON(raw, ledgers); err != nil {
+			return nil, fmt.Errorf("chain at index %d: %w", s.index-1, err)
+		}
+		chain = []Transfer{transfer}
+	} else {
+		chain, err = UnmarshalTransfers(group.Chain, ledgers)
+		if err != nil {
+			return nil, fmt.Errorf("chain at index %d: %w", s.index-1, err)
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("chain at index %d: chain is empty", s.index-1)
+		}
+	}
+
+	linked := types.TransferFlags{Linked: true}.ToUint16()
+	for i := range chain {
+		if i < len(chain)-1 {
+			chain[i].Flags |= linked
+		} else {
+			chain[i].Flags &^= linked
+		}
+	}
+	return chain, nil
+}
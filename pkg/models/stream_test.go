@@ -0,0 +1,132 @@
+package models
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllTransfers(t *testing.T, input string) ([]Transfer, *RecordStream) {
+	stream := NewRecordStream(strings.NewReader(input))
+	var transfers []Transfer
+	for {
+		var transfer Transfer
+		err := stream.NextTransfer(&transfer, nil)
+		if err == io.EOF {
+			return transfers, stream
+		}
+		require.NoError(t, err)
+		transfers = append(transfers, transfer)
+	}
+}
+
+func TestRecordStreamFormats(t *testing.T) {
+	array := `  [
+		{"id": 1, "amount": 10},
+		{"id": 2, "amount": 20}
+	]`
+	ndjson := "{\"id\": 1, \"amount\": 10}\n{\"id\": 2, \"amount\": 20}\n"
+
+	for name, input := range map[string]string{"array": array, "ndjson": ndjson} {
+		t.Run(name, func(t *testing.T) {
+			transfers, stream := readAllTransfers(t, input)
+			require.Len(t, transfers, 2)
+			assert.Equal(t, "20", FormatUint128(transfers[1].Amount))
+			assert.Equal(t, 2, stream.Index())
+		})
+	}
+
+	transfers, _ := readAllTransfers(t, "")
+	assert.Empty(t, transfers)
+}
+
+func TestRecordStreamOffset(t *testing.T) {
+	input := "{\"id\": 1}\n{\"id\": 2}\n"
+	stream := NewRecordStream(strings.NewReader(input))
+
+	_, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("{\"id\": 1}")), stream.Offset())
+
+	_, err = stream.Next()
+	require.NoError(t, err)
+	_, err = stream.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestResumeRecordStream(t *testing.T) {
+	array := `[
+		{"id": 1, "amount": 10} ,
+		{"id": 2, "amount": 20},{"id": 3, "amount": 30}
+	]`
+	ndjson := "{\"id\": 1, \"amount\": 10}\n{\"id\": 2, \"amount\": 20}\n{\"id\": 3, \"amount\": 30}\n"
+
+	for name, input := range map[string]string{"array": array, "ndjson": ndjson} {
+		t.Run(name, func(t *testing.T) {
+			full := NewRecordStream(strings.NewReader(input))
+			var offsets []int64
+			for {
+				if _, err := full.Next(); err == io.EOF {
+					break
+				}
+				offsets = append(offsets, full.Offset())
+			}
+			require.Len(t, offsets, 3)
+
+			stream, err := ResumeRecordStream(strings.NewReader(input), offsets[0], 1)
+			require.NoError(t, err)
+			var transfer Transfer
+			require.NoError(t, stream.NextTransfer(&transfer, nil))
+			assert.Equal(t, "2", FormatUint128(transfer.ID))
+			assert.Equal(t, 2, stream.Index())
+			assert.Equal(t, offsets[1], stream.Offset())
+
+			require.NoError(t, stream.NextTransfer(&transfer, nil))
+			assert.Equal(t, "3", FormatUint128(transfer.ID))
+			assert.Equal(t, io.EOF, stream.NextTransfer(&transfer, nil))
+
+			// Resuming after the last record leaves nothing to read.
+			stream, err = ResumeRecordStream(strings.NewReader(input), offsets[2], 3)
+			require.NoError(t, err)
+			assert.Equal(t, io.EOF, stream.NextTransfer(&transfer, nil))
+		})
+	}
+}
+
+func TestRecordStreamReportsIndex(t *testing.T) {
+	stream := NewRecordStream(strings.NewReader(`[{"id": 1}, {"id": "x"}]`))
+
+	var account Account
+	require.NoError(t, stream.NextAccount(&account, nil))
+	err := stream.NextAccount(&account, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account at index 1")
+	assert.Contains(t, err.Error(), "field id")
+}
+
+func TestRecordStreamTransferChains(t *testing.T) {
+	input := `{"id": 1, "amount": 10, "flags": "linked"}
+{"chain": [{"id": 2, "amount": 20}, {"id": 3, "amount": 30, "flags": "pending"}, {"id": 4, "amount": 40, "flags": "linked"}]}
+{"chain": []}
+`
+	stream := NewRecordStream(strings.NewReader(input))
+
+	// A lone transfer is a chain of one and must not be left open.
+	chain, err := stream.NextTransferChain(nil)
+	require.NoError(t, err)
+	require.Len(t, chain, 1)
+	assert.Empty(t, TransferFlagNames(chain[0].Flags))
+
+	chain, err = stream.NextTransferChain(nil)
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	assert.Equal(t, []string{"linked"}, TransferFlagNames(chain[0].Flags))
+	assert.Equal(t, []string{"linked", "pending"}, TransferFlagNames(chain[1].Flags))
+	assert.Empty(t, TransferFlagNames(chain[2].Flags))
+
+	_, err = stream.NextTransferChain(nil)
+	assert.ErrorContains(t, err, "chain at index 2: chain is empty")
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
@@ -55,3 +58,121 @@ func FromTigerBeetleTransfer(tbt types.Transfer) *Transfer {
 		Timestamp:       tbt.Timestamp,
 	}
 }
+
+func (t Transfer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		ID              string `json:"id"`
+		DebitAccountID  string `json:"debit_account_id"`
+		CreditAccountID string `json:"credit_account_id"`
+		Amount          string `json:"amount"`
+		PendingID       string `json:"pending_id"`
+		UserData128     string `json:"user_data_128"`
+		UserData64      uint64 `json:"user_data_64"`
+		UserData32      uint32 `json:"user_data_32"`
+		Timeout         uint32 `json:"timeout"`
+		Ledger          uint32 `json:"ledger"`
+		Code            uint16 `json:"code"`
+		Flags           uint16 `json:"flags"`
+		Timestamp       uint64 `json:"timestamp"`
+	}{
+		ID:              FormatUint128(t.ID),
+		DebitAccountID:  FormatUint128(t.DebitAccountID),
+		CreditAccountID: FormatUint128(t.CreditAccountID),
+		Amount:          FormatUint128(t.Amount),
+		PendingID:       FormatUint128(t.PendingID),
+		UserData128:     FormatUint128(t.UserData128),
+		UserData64:      t.UserData64,
+		UserData32:      t.UserData32,
+		Timeout:         t.Timeout,
+		Ledger:          t.Ledger,
+		Code:            t.Code,
+		Flags:           t.Flags,
+		Timestamp:       t.Timestamp,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. 128-bit fields
+// accept the same forms as Account.UnmarshalJSON.
+func (t *Transfer) UnmarshalJSON(data []byte) error {
+	return t.unmarshalJSON(data, nil)
+}
+
+func (t *Transfer) unmarshalJSON(data []byte, ledgers LedgerRegistry) error {
+	aux := &struct {
+		ID              json.RawMessage `json:"id"`
+		DebitAccountID  json.RawMessage `json:"debit_account_id"`
+		CreditAccountID json.RawMessage `json:"credit_account_id"`
+		Amount          json.RawMessage `json:"amount"`
+		PendingID       json.RawMessage `json:"pending_id"`
+		UserData128     json.RawMessage `json:"user_data_128"`
+		UserData64      uint64          `json:"user_data_64"`
+		UserData32      uint32          `json:"user_data_32"`
+		Timeout         uint32          `json:"timeout"`
+		Ledger          uint32          `json:"ledger"`
+		Code            uint16          `json:"code"`
+		Flags           json.RawMessage `json:"flags"`
+		Timestamp       uint64          `json:"timestamp"`
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name   string
+		raw    json.RawMessage
+		dst    *types.Uint128
+		amount bool
+	}{
+		{"id", aux.ID, &t.ID, false},
+		{"debit_account_id", aux.DebitAccountID, &t.DebitAccountID, false},
+		{"credit_account_id", aux.CreditAccountID, &t.CreditAccountID, false},
+		{"amount", aux.Amount, &t.Amount, true},
+		{"pending_id", aux.PendingID, &t.PendingID, false},
+		{"user_data_128", aux.UserData128, &t.UserData128, false},
+	}
+	for _, f := range fields {
+		var value types.Uint128
+		var err error
+		if f.amount {
+			value, err = parseAmountField(f.name, f.raw, aux.Ledger, ledgers)
+		} else {
+			value, err = parseUint128Field(f.name, f.raw)
+		}
+		if err != nil {
+			return err
+		}
+		*f.dst = value
+	}
+
+	flags, err := parseFlagsField(aux.Flags, ParseTransferFlags)
+	if err != nil {
+		return err
+	}
+	t.Flags = flags
+
+	t.UserData64 = aux.UserData64
+	t.UserData32 = aux.UserData32
+	t.Timeout = aux.Timeout
+	t.Ledger = aux.Ledger
+	t.Code = aux.Code
+	t.Timestamp = aux.Timestamp
+	return nil
+}
+
+// UnmarshalTransfers decodes a JSON array of transfers, reporting the index of
+// the first record that fails to decode. Amounts given as decimals are scaled
+// using the ledger registry.
+func UnmarshalTransfers(data []byte, ledgers LedgerRegistry) ([]Transfer, error) {
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	transfers := make([]Transfer, len(records))
+	for i, record := range records {
+		if err := transfers[i].unmarshalJSON(record, ledgers); err != nil {
+			return nil, fmt.Errorf("transfer at index %d: %w", i, err)
+		}
+	}
+	return transfers, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func TestTransferJSONRoundTrip(t *testing.T) {
+	id, err := ParseUint128("0190b5d6-c2f0-4f6b-8e4a-1b2c3d4e5f60")
+	require.NoError(t, err)
+
+	transfer := Transfer{
+		ID:              id,
+		DebitAccountID:  types.ToUint128(1),
+		CreditAccountID: types.ToUint128(2),
+		Amount:          types.ToUint128(100),
+		PendingID:       types.ToUint128(3),
+		UserData128:     types.ToUint128(4),
+		UserData64:      5,
+		UserData32:      6,
+		Timeout:         7,
+		Ledger:          700,
+		Code:            10,
+		Flags:           2,
+		Timestamp:       1700000000000000000,
+	}
+	data, err := json.Marshal(transfer)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"debit_account_id":"1"`)
+	assert.Contains(t, string(data), `"user_data_128":"4"`)
+
+	var decoded Transfer
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, transfer, decoded)
+}
+
+func TestUnmarshalTransfersReportsFieldAndIndex(t *testing.T) {
+	data := []byte(`[
+		{"id": 1, "debit_account_id": 1, "credit_account_id": 2, "amount": 10, "ledger": 700, "code": 10},
+		{"id": 2, "debit_account_id": 1, "credit_account_id": 2, "amount": -10, "ledger": 700, "code": 10}
+	]`)
+
+	_, err := UnmarshalTransfers(data, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index 1")
+	assert.Contains(t, err.Error(), "amount")
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+var maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// ParseUint128 parses a 128-bit unsigned integer. Plain digits are read as
+// decimal, a "0x" prefix selects hex, and the canonical 8-4-4-4-12 form is
+// read as a big-endian UUID.
+func ParseUint128(s string) (types.Uint128, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return types.Uint128{}, fmt.Errorf("empty value")
+	}
+
+	if isUUID(s) {
+		s = "0x" + strings.ReplaceAll(s, "-", "")
+	}
+
+	value := new(big.Int)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits := s[2:]
+		if len(digits) == 0 || len(digits) > 32 {
+			return types.Uint128{}, fmt.Errorf("invalid hex value %q", s)
+		}
+		if _, ok := value.SetString(digits, 16); !ok {
+			return types.Uint128{}, fmt.Errorf("invalid hex value %q", s)
+		}
+	} else {
+		for _, r := range s {
+			if r < '0' || r > '9' {
+				return types.Uint128{}, fmt.Errorf("invalid decimal value %q", s)
+			}
+		}
+		value.SetString(s, 10)
+	}
+
+	if value.Cmp(maxUint128) > 0 {
+		return types.Uint128{}, fmt.Errorf("value %s exceeds maximum uint128", s)
+	}
+	return types.BigIntToUint128(*value), nil
+}
+
+// FormatUint128 formats a 128-bit unsigned integer as a decimal string.
+func FormatUint128(u types.Uint128) string {
+	value := u.BigInt()
+	return value.String()
+}
+
+// isUUID reports whether s has the canonical 8-4-4-4-12 UUID layout.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseUint128Field decodes a JSON string or number into a Uint128. A missing
+// or null field decodes to zero. Errors carry the JSON field name.
+func parseUint128Field(field string, raw json.RawMessage) (types.Uint128, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return types.Uint128{}, nil
+	}
+
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return types.Uint128{}, fmt.Errorf("field %s: %w", field, err)
+		}
+	} else {
+		s = string(raw)
+	}
+
+	value, err := ParseUint128(s)
+	if err != nil {
+		return types.Uint128{}, fmt.Errorf("field %s: %w", field, err)
+	}
+	return value, nil
+}
+
+// parseAmountField is parseUint128Field for amount fields, which additionally
+// accept decimals in major units of the ledger's asset.
+func parseAmountField(field string, raw json.RawMessage, ledger uint32, ledgers LedgerRegistry) (types.Uint128, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return types.Uint128{}, nil
+	}
+
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return types.Uint128{}, fmt.Errorf("field %s: %w", field, err)
+		}
+	} else {
+		s = string(raw)
+	}
+
+	value, err := ledgers.ParseAmount(s, ledger)
+	if err != nil {
+		return types.Uint128{}, fmt.Errorf("field %s: %w", field, err)
+	}
+	return value, nil
+}